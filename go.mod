@@ -62,12 +62,15 @@ replace k8s.io/sample-controller => k8s.io/sample-controller v0.27.1
 
 require (
 	github.com/golang/protobuf v1.5.3
+	go.uber.org/goleak v1.2.1
 	google.golang.org/grpc v1.51.0
 	k8s.io/api v0.27.1
 	k8s.io/apimachinery v0.27.1
 	k8s.io/client-go v0.27.1
 	k8s.io/component-base v0.27.1
+	k8s.io/klog/v2 v2.90.1
 	k8s.io/kubernetes v1.27.1
+	k8s.io/utils v0.0.0-20230209194617-a36077c30491
 )
 
 require (
@@ -160,13 +163,11 @@ require (
 	k8s.io/controller-manager v0.27.1 // indirect
 	k8s.io/csi-translation-lib v0.25.7 // indirect
 	k8s.io/dynamic-resource-allocation v0.0.0 // indirect
-	k8s.io/klog/v2 v2.90.1 // indirect
 	k8s.io/kms v0.27.1 // indirect
 	k8s.io/kube-openapi v0.0.0-20230308215209-15aac26d736a // indirect
 	k8s.io/kube-scheduler v0.25.7 // indirect
 	k8s.io/kubelet v0.27.1 // indirect
 	k8s.io/mount-utils v0.25.7 // indirect
-	k8s.io/utils v0.0.0-20230209194617-a36077c30491 // indirect
 	sigs.k8s.io/apiserver-network-proxy/konnectivity-client v0.1.1 // indirect
 	sigs.k8s.io/json v0.0.0-20221116044647-bc3834ca7abd // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.2.3 // indirect
@@ -0,0 +1,439 @@
+package plugins
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// maxDurationSeconds is the most seconds a time.Duration holds, larger
+// values in the *Seconds fields would overflow when converted.
+const maxDurationSeconds = math.MaxInt64 / int64(time.Second)
+
+// Validate checks enum values, numeric ranges and conflicting fields of the
+// plugin args, and reports every problem found rather than the first one. It
+// never panics on any decoded input, which keeps it cheap to fuzz.
+func (args *CustomSchedulerArgs) Validate() error {
+	var errs []error
+
+	if !isValidMode(args.Mode) {
+		errs = append(errs, fmt.Errorf("%w, got %s in the plugin's args.mode, accepted modes are %s", ErrInvalidMode, args.Mode, acceptedModes()))
+	}
+	namespaces := make([]string, 0, len(args.NamespaceModes))
+	for ns := range args.NamespaceModes {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	for _, ns := range namespaces {
+		if mode := args.NamespaceModes[ns]; !isValidMode(mode) {
+			errs = append(errs, errorWithSentinel(ErrInvalidMode, "invalid namespaceModes mode for namespace %s, got %s in the plugin's args.namespaceModes, accepted modes are %s", ns, mode, acceptedModes()))
+		}
+	}
+	profiles := make([]string, 0, len(args.ScoringProfiles))
+	for name := range args.ScoringProfiles {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	for _, name := range profiles {
+		profile := args.ScoringProfiles[name]
+		if msgs := validation.IsValidLabelValue(name); len(msgs) > 0 || name == "" {
+			errs = append(errs, fmt.Errorf("invalid scoringProfiles name %q, must be a label value", name))
+		}
+		if profile.Mode != "" && !isValidMode(profile.Mode) {
+			errs = append(errs, errorWithSentinel(ErrInvalidMode, "invalid scoringProfiles mode for profile %s, got %s in the plugin's args.scoringProfiles, accepted modes are %s", name, profile.Mode, acceptedModes()))
+		}
+		switch profile.ScoreBy {
+		case "", memoryScoreBy, podCapacityScoreBy:
+		default:
+			errs = append(errs, fmt.Errorf("invalid scoreBy for scoring profile %s, got %s", name, profile.ScoreBy))
+		}
+		if profile.CostWeight < 0 || profile.PreferredNodeLabelsWeight < 0 {
+			errs = append(errs, fmt.Errorf("invalid weights for scoring profile %s, must be non-negative", name))
+		}
+	}
+	switch args.ScoreCurve {
+	case "", linearCurve, logCurve, stepCurve:
+	default:
+		errs = append(errs, fmt.Errorf("invalid score curve, got %s", args.ScoreCurve))
+	}
+	// the curve reshapes resource values, BinPack and Balanced already score
+	// ratios and the pod headroom modes score small counts
+	switch args.Mode {
+	case binPackMode, balancedMode, dominantMode, leastPodHeadroomMode, mostPodHeadroomMode:
+		if args.ScoreCurve != "" && args.ScoreCurve != linearCurve {
+			errs = append(errs, fmt.Errorf("score curve %s can't be combined with mode %s", args.ScoreCurve, args.Mode))
+		}
+	}
+	// every node would saturate the framework's range without
+	// NormalizeScore, whichever way the mode is selected
+	if !args.NormalizeScores {
+		if scoresRawQuantity(args.Mode) {
+			errs = append(errs, fmt.Errorf("mode %s requires normalizeScores, its raw scores don't fit the framework's range", args.Mode))
+		}
+		for _, ns := range namespaces {
+			if mode := args.NamespaceModes[ns]; scoresRawQuantity(mode) {
+				errs = append(errs, fmt.Errorf("namespaceModes mode %s for namespace %s requires normalizeScores, its raw scores don't fit the framework's range", mode, ns))
+			}
+		}
+		for _, name := range profiles {
+			if mode := args.ScoringProfiles[name].Mode; scoresRawQuantity(mode) {
+				errs = append(errs, fmt.Errorf("scoringProfiles mode %s for profile %s requires normalizeScores, its raw scores don't fit the framework's range", mode, name))
+			}
+		}
+	}
+
+	if args.PackRatio < 0 || args.PackRatio > 1 || math.IsNaN(args.PackRatio) {
+		errs = append(errs, fmt.Errorf("invalid packRatio, must be in [0,1], got %v", args.PackRatio))
+	}
+	if args.PackRatio != 0 && args.Mode != blendMode {
+		errs = append(errs, fmt.Errorf("packRatio is only used by mode %s", blendMode))
+	}
+
+	switch args.DominantPreference {
+	case "", leastMode, mostMode:
+	default:
+		errs = append(errs, fmt.Errorf("invalid dominantPreference, must be %s or %s, got %s", leastMode, mostMode, args.DominantPreference))
+	}
+	if args.DominantPreference != "" && args.Mode != dominantMode {
+		errs = append(errs, fmt.Errorf("dominantPreference is only used by mode %s", dominantMode))
+	}
+
+	if args.DefaultMinAvailable < 0 {
+		errs = append(errs, fmt.Errorf("invalid defaultMinAvailable, must be non-negative, got %d", args.DefaultMinAvailable))
+	}
+
+	if args.GPUMemoryResource != "" {
+		if msgs := validation.IsQualifiedName(args.GPUMemoryResource); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid gpuMemoryResource %q: %s", args.GPUMemoryResource, strings.Join(msgs, "; ")))
+		}
+	}
+
+	if args.MinAvailableAnnotation != "" {
+		if msgs := validation.IsQualifiedName(args.MinAvailableAnnotation); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid minAvailableAnnotation %q: %s", args.MinAvailableAnnotation, strings.Join(msgs, "; ")))
+		}
+	}
+
+	if args.AnnotationKey != "" {
+		if msgs := validation.IsQualifiedName(args.AnnotationKey); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid annotationKey %q: %s", args.AnnotationKey, strings.Join(msgs, "; ")))
+		}
+	} else if args.usesAnnotationMode() {
+		errs = append(errs, fmt.Errorf("annotationKey must be set with modes %s and %s", leastAnnotationMode, mostAnnotationMode))
+	}
+
+	components := make([]string, 0, len(args.ComponentCaps))
+	for component := range args.ComponentCaps {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		switch component {
+		case resourceComponent, costComponent, affinityComponent:
+		default:
+			errs = append(errs, fmt.Errorf("invalid componentCaps component %q, must be %s, %s or %s", component, resourceComponent, costComponent, affinityComponent))
+			continue
+		}
+		if limit := args.ComponentCaps[component]; limit <= 0 {
+			errs = append(errs, fmt.Errorf("invalid componentCaps cap for %s, must be positive, got %d", component, limit))
+		}
+	}
+	if args.ScoreClamp < 0 {
+		errs = append(errs, fmt.Errorf("invalid scoreClamp, must be non-negative, got %d", args.ScoreClamp))
+	}
+	if args.MaxRawScore < 0 {
+		errs = append(errs, fmt.Errorf("invalid maxRawScore, must be non-negative, got %d", args.MaxRawScore))
+	}
+	if args.MaxRawScore > 0 && args.ScoreClamp > 0 && args.MaxRawScore != args.ScoreClamp {
+		errs = append(errs, fmt.Errorf("invalid maxRawScore %d, it aliases scoreClamp which is %d", args.MaxRawScore, args.ScoreClamp))
+	}
+
+	switch args.ScoreBy {
+	case "", memoryScoreBy, podCapacityScoreBy:
+	default:
+		errs = append(errs, fmt.Errorf("invalid scoreBy, got %s", args.ScoreBy))
+	}
+	switch args.FreeMemoryBasis {
+	case "", allocatableBasis, allocatableMinusRequestedBasis:
+	default:
+		errs = append(errs, fmt.Errorf("invalid freeMemoryBasis, got %s, accepted bases are %s and %s", args.FreeMemoryBasis, allocatableBasis, allocatableMinusRequestedBasis))
+	}
+
+	if args.HardFailAfterSeconds < 0 {
+		errs = append(errs, fmt.Errorf("invalid hardFailAfterSeconds, got %d", args.HardFailAfterSeconds))
+	} else if args.HardFailAfterSeconds > maxDurationSeconds {
+		errs = append(errs, fmt.Errorf("invalid hardFailAfterSeconds, must be at most %d, got %d", maxDurationSeconds, args.HardFailAfterSeconds))
+	}
+
+	if args.GroupStabilizationSeconds < 0 {
+		errs = append(errs, fmt.Errorf("invalid groupStabilizationSeconds, got %d", args.GroupStabilizationSeconds))
+	} else if args.GroupStabilizationSeconds > maxDurationSeconds {
+		errs = append(errs, fmt.Errorf("invalid groupStabilizationSeconds, must be at most %d, got %d", maxDurationSeconds, args.GroupStabilizationSeconds))
+	}
+
+	if args.MaxActiveGroups < 0 {
+		errs = append(errs, fmt.Errorf("invalid maxActiveGroups, got %d", args.MaxActiveGroups))
+	}
+	if args.ActiveGroupTTLSeconds < 0 {
+		errs = append(errs, fmt.Errorf("invalid activeGroupTTLSeconds, got %d", args.ActiveGroupTTLSeconds))
+	} else if args.ActiveGroupTTLSeconds > maxDurationSeconds {
+		errs = append(errs, fmt.Errorf("invalid activeGroupTTLSeconds, must be at most %d, got %d", maxDurationSeconds, args.ActiveGroupTTLSeconds))
+	}
+
+	if args.NormalizeMin != 0 || args.NormalizeMax != 0 {
+		if args.NormalizeMin < framework.MinNodeScore || args.NormalizeMax > framework.MaxNodeScore {
+			errs = append(errs, fmt.Errorf("normalizeMin and normalizeMax must be within [%d,%d], got [%d,%d]", framework.MinNodeScore, framework.MaxNodeScore, args.NormalizeMin, args.NormalizeMax))
+		}
+		if args.NormalizeMin >= args.NormalizeMax {
+			errs = append(errs, fmt.Errorf("normalizeMin must be less than normalizeMax, got [%d,%d]", args.NormalizeMin, args.NormalizeMax))
+		}
+	}
+
+	if args.MinFreeMemoryBytes != "" {
+		if q, err := resource.ParseQuantity(args.MinFreeMemoryBytes); err != nil {
+			errs = append(errs, fmt.Errorf("invalid minFreeMemoryBytes: %v", err))
+		} else if q.Sign() < 0 {
+			errs = append(errs, fmt.Errorf("invalid minFreeMemoryBytes, got %s", args.MinFreeMemoryBytes))
+		}
+	}
+
+	if args.PreferredNodeLabelsWeight < 0 {
+		errs = append(errs, fmt.Errorf("invalid preferredNodeLabelsWeight, got %d", args.PreferredNodeLabelsWeight))
+	}
+	if args.PreferredNodeLabelsWeight > 0 && len(args.PreferredNodeLabels) == 0 {
+		errs = append(errs, fmt.Errorf("preferredNodeLabelsWeight is set without preferredNodeLabels"))
+	}
+	labelKeys := make([]string, 0, len(args.PreferredNodeLabels))
+	for k := range args.PreferredNodeLabels {
+		labelKeys = append(labelKeys, k)
+	}
+	sort.Strings(labelKeys)
+	for _, k := range labelKeys {
+		v := args.PreferredNodeLabels[k]
+		if msgs := validation.IsQualifiedName(k); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid preferredNodeLabels key %q: %s", k, strings.Join(msgs, "; ")))
+		}
+		if msgs := validation.IsValidLabelValue(v); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid preferredNodeLabels value %q: %s", v, strings.Join(msgs, "; ")))
+		}
+	}
+
+	if args.PreferredBonus < 0 {
+		errs = append(errs, fmt.Errorf("invalid preferredBonus, got %d", args.PreferredBonus))
+	}
+	if args.PreferredNodeLabel != "" {
+		key, value, err := parsePreferredNodeLabel(args.PreferredNodeLabel)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid preferredNodeLabel: %v", err))
+		} else {
+			if msgs := validation.IsQualifiedName(key); len(msgs) > 0 {
+				errs = append(errs, fmt.Errorf("invalid preferredNodeLabel key %q: %s", key, strings.Join(msgs, "; ")))
+			}
+			if msgs := validation.IsValidLabelValue(value); len(msgs) > 0 {
+				errs = append(errs, fmt.Errorf("invalid preferredNodeLabel value %q: %s", value, strings.Join(msgs, "; ")))
+			}
+		}
+		if args.PreferredBonus == 0 {
+			errs = append(errs, fmt.Errorf("preferredBonus must be set with preferredNodeLabel"))
+		}
+	} else if args.PreferredBonus > 0 {
+		errs = append(errs, fmt.Errorf("preferredBonus is set without preferredNodeLabel"))
+	}
+
+	if args.CostWeight < 0 {
+		errs = append(errs, fmt.Errorf("invalid costWeight, got %d", args.CostWeight))
+	}
+	if args.CostLabel != "" {
+		if msgs := validation.IsQualifiedName(args.CostLabel); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid costLabel %q: %s", args.CostLabel, strings.Join(msgs, "; ")))
+		}
+		if args.CostWeight == 0 {
+			errs = append(errs, fmt.Errorf("costWeight must be set with costLabel"))
+		}
+	} else if args.CostWeight > 0 {
+		errs = append(errs, fmt.Errorf("costWeight is set without costLabel"))
+	}
+
+	if args.ColdNodeWindowSeconds < 0 {
+		errs = append(errs, fmt.Errorf("invalid coldNodeWindowSeconds, must be non-negative, got %d", args.ColdNodeWindowSeconds))
+	} else if args.ColdNodeWindowSeconds > maxDurationSeconds {
+		errs = append(errs, fmt.Errorf("invalid coldNodeWindowSeconds, must be at most %d, got %d", maxDurationSeconds, args.ColdNodeWindowSeconds))
+	}
+	if args.ColdNodePenaltyPercent < 0 || args.ColdNodePenaltyPercent > 100 {
+		errs = append(errs, fmt.Errorf("invalid coldNodePenaltyPercent, must be in [0,100], got %d", args.ColdNodePenaltyPercent))
+	}
+	if (args.ColdNodeWindowSeconds > 0) != (args.ColdNodePenaltyPercent > 0) {
+		errs = append(errs, fmt.Errorf("coldNodeWindowSeconds and coldNodePenaltyPercent must be set together"))
+	}
+
+	if args.PressurePenaltyPercent < 0 || args.PressurePenaltyPercent > 100 {
+		errs = append(errs, fmt.Errorf("invalid pressurePenaltyPercent, must be in [0,100], got %d", args.PressurePenaltyPercent))
+	}
+	if args.FilterPressuredNodes && args.PressurePenaltyPercent > 0 {
+		errs = append(errs, fmt.Errorf("pressurePenaltyPercent can't be combined with filterPressuredNodes"))
+	}
+
+	// the penalty is applied in NormalizeScore
+	if args.SoftGang && !args.NormalizeScores {
+		errs = append(errs, fmt.Errorf("softGang requires normalizeScores"))
+	}
+
+	switch args.GangGatePhase {
+	case "", prefilterGatePhase:
+	case permitGatePhase:
+		if args.SoftGang {
+			errs = append(errs, fmt.Errorf("gangGatePhase %s can't be combined with softGang", permitGatePhase))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid gangGatePhase, got %s, accepted phases are %s and %s", args.GangGatePhase, prefilterGatePhase, permitGatePhase))
+	}
+
+	if (args.AllocatableOverrideConfigMap != "") != (args.AllocatableOverrideNamespace != "") {
+		errs = append(errs, fmt.Errorf("allocatableOverrideConfigMap and allocatableOverrideNamespace must be set together"))
+	}
+	if args.AllocatableOverrideConfigMap != "" {
+		if msgs := validation.IsDNS1123Subdomain(args.AllocatableOverrideConfigMap); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid allocatableOverrideConfigMap %q: %s", args.AllocatableOverrideConfigMap, strings.Join(msgs, "; ")))
+		}
+	}
+	if args.AllocatableOverrideNamespace != "" {
+		if msgs := validation.IsDNS1123Label(args.AllocatableOverrideNamespace); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid allocatableOverrideNamespace %q: %s", args.AllocatableOverrideNamespace, strings.Join(msgs, "; ")))
+		}
+	}
+
+	if args.ListerBreakerThreshold < 0 {
+		errs = append(errs, fmt.Errorf("invalid listerBreakerThreshold, must be non-negative, got %d", args.ListerBreakerThreshold))
+	}
+	if args.ListerBreakerWindowSeconds < 0 {
+		errs = append(errs, fmt.Errorf("invalid listerBreakerWindowSeconds, must be non-negative, got %d", args.ListerBreakerWindowSeconds))
+	} else if args.ListerBreakerWindowSeconds > maxDurationSeconds {
+		errs = append(errs, fmt.Errorf("invalid listerBreakerWindowSeconds, must be at most %d, got %d", maxDurationSeconds, args.ListerBreakerWindowSeconds))
+	}
+	if (args.ListerBreakerThreshold > 0) != (args.ListerBreakerWindowSeconds > 0) {
+		errs = append(errs, fmt.Errorf("listerBreakerThreshold and listerBreakerWindowSeconds must be set together"))
+	}
+
+	if args.GangTimeoutSeconds < 0 {
+		errs = append(errs, fmt.Errorf("invalid gangTimeoutSeconds, must be non-negative, got %d", args.GangTimeoutSeconds))
+	} else if args.GangTimeoutSeconds > maxDurationSeconds {
+		errs = append(errs, fmt.Errorf("invalid gangTimeoutSeconds, must be at most %d, got %d", maxDurationSeconds, args.GangTimeoutSeconds))
+	}
+	if args.DeleteStuckGangs && args.GangTimeoutSeconds <= 0 {
+		errs = append(errs, fmt.Errorf("deleteStuckGangs requires gangTimeoutSeconds"))
+	}
+
+	if args.GangBackoffSeconds < 0 {
+		errs = append(errs, fmt.Errorf("invalid gangBackoffSeconds, must be non-negative, got %d", args.GangBackoffSeconds))
+	} else if args.GangBackoffSeconds > maxDurationSeconds {
+		errs = append(errs, fmt.Errorf("invalid gangBackoffSeconds, must be at most %d, got %d", maxDurationSeconds, args.GangBackoffSeconds))
+	}
+	if args.MaxGangBackoffSeconds < 0 {
+		errs = append(errs, fmt.Errorf("invalid maxGangBackoffSeconds, must be non-negative, got %d", args.MaxGangBackoffSeconds))
+	} else if args.MaxGangBackoffSeconds > maxDurationSeconds {
+		errs = append(errs, fmt.Errorf("invalid maxGangBackoffSeconds, must be at most %d, got %d", maxDurationSeconds, args.MaxGangBackoffSeconds))
+	}
+	if args.MaxGangBackoffSeconds > 0 && args.GangBackoffSeconds == 0 {
+		errs = append(errs, fmt.Errorf("maxGangBackoffSeconds requires gangBackoffSeconds"))
+	}
+
+	if args.GroupNameNormalizer != nil {
+		if args.GroupNameNormalizer.Pattern == "" {
+			errs = append(errs, fmt.Errorf("groupNameNormalizer requires a pattern"))
+		} else if _, err := regexp.Compile(args.GroupNameNormalizer.Pattern); err != nil {
+			errs = append(errs, fmt.Errorf("invalid groupNameNormalizer pattern: %v", err))
+		}
+	}
+
+	if args.GroupTopologyKey != "" {
+		if msgs := validation.IsQualifiedName(args.GroupTopologyKey); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid groupTopologyKey %q: %s", args.GroupTopologyKey, strings.Join(msgs, "; ")))
+		}
+	}
+	if args.GroupMaxSkew < 0 {
+		errs = append(errs, fmt.Errorf("invalid groupMaxSkew, must be non-negative, got %d", args.GroupMaxSkew))
+	}
+	if args.GroupMaxSkew > 0 && args.GroupTopologyKey == "" {
+		errs = append(errs, fmt.Errorf("groupMaxSkew requires groupTopologyKey"))
+	}
+
+	for _, ns := range args.ExcludedNamespaces {
+		if msgs := validation.IsDNS1123Label(ns); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid excludedNamespaces entry %q: %s", ns, strings.Join(msgs, "; ")))
+		}
+	}
+
+	for _, phase := range args.CountPhases {
+		switch v1.PodPhase(phase) {
+		case "", v1.PodPending, v1.PodRunning, v1.PodSucceeded, v1.PodFailed, v1.PodUnknown:
+		default:
+			errs = append(errs, fmt.Errorf("invalid countPhases entry, got %s", phase))
+		}
+	}
+
+	if args.GroupByOwner && len(args.GroupBy) > 0 {
+		errs = append(errs, fmt.Errorf("groupBy can't be combined with groupByOwner"))
+	}
+	for _, key := range args.GroupBy {
+		if msgs := validation.IsQualifiedName(key); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid groupBy key %q: %s", key, strings.Join(msgs, "; ")))
+		}
+	}
+
+	if keys := splitLabelKeys(args.GroupNameLabels); len(keys) > 0 {
+		if args.GroupByOwner || len(args.GroupBy) > 0 {
+			errs = append(errs, fmt.Errorf("groupNameLabels can't be combined with groupBy or groupByOwner"))
+		}
+		for _, key := range keys {
+			if msgs := validation.IsQualifiedName(key); len(msgs) > 0 {
+				errs = append(errs, fmt.Errorf("invalid groupNameLabels key %q: %s", key, strings.Join(msgs, "; ")))
+			}
+		}
+	}
+
+	if args.GroupExcludeLabel != "" {
+		if msgs := validation.IsQualifiedName(args.GroupExcludeLabel); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid groupExcludeLabel %q: %s", args.GroupExcludeLabel, strings.Join(msgs, "; ")))
+		}
+	}
+
+	if args.GroupSelectorExpressions != nil {
+		if _, err := metav1.LabelSelectorAsSelector(args.GroupSelectorExpressions); err != nil {
+			errs = append(errs, fmt.Errorf("invalid groupSelectorExpressions: %v", err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// usesAnnotationMode reports whether the args configure a mode scoring by a
+// node annotation, as the mode, a namespace mode or a profile mode.
+func (args *CustomSchedulerArgs) usesAnnotationMode() bool {
+	isAnnotationMode := func(mode ScoreMode) bool {
+		return mode == leastAnnotationMode || mode == mostAnnotationMode
+	}
+	if isAnnotationMode(args.Mode) {
+		return true
+	}
+	for _, mode := range args.NamespaceModes {
+		if isAnnotationMode(mode) {
+			return true
+		}
+	}
+	for _, profile := range args.ScoringProfiles {
+		if isAnnotationMode(profile.Mode) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,306 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestCustomSchedulerArgs_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		args CustomSchedulerArgs
+		// wantErrs are substrings expected in the error, none means valid.
+		wantErrs []string
+	}{
+		{name: "least mode", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true}},
+		{name: "most mode", args: CustomSchedulerArgs{Mode: mostMode, NormalizeScores: true}},
+		{name: "bin pack mode", args: CustomSchedulerArgs{Mode: binPackMode}},
+		{name: "raw scores with balanced mode", args: CustomSchedulerArgs{Mode: balancedMode}},
+		{name: "raw scores with least mode", args: CustomSchedulerArgs{Mode: leastMode}, wantErrs: []string{"mode Least requires normalizeScores"}},
+		{name: "raw scores with most mode", args: CustomSchedulerArgs{Mode: mostMode}, wantErrs: []string{"mode Most requires normalizeScores"}},
+		{name: "raw scores with gpu memory mode", args: CustomSchedulerArgs{Mode: mostGPUMemoryMode}, wantErrs: []string{"mode MostGPUMemory requires normalizeScores"}},
+		{name: "raw scores with blend mode", args: CustomSchedulerArgs{Mode: blendMode, PackRatio: 0.5}, wantErrs: []string{"mode Blend requires normalizeScores"}},
+		{
+			name:     "raw scores with a namespace mode",
+			args:     CustomSchedulerArgs{Mode: balancedMode, NamespaceModes: map[string]ScoreMode{"batch": leastEphemeralStorageMode}},
+			wantErrs: []string{"namespaceModes mode LeastEphemeralStorage for namespace batch requires normalizeScores"},
+		},
+		{
+			name:     "raw scores with a profile mode",
+			args:     CustomSchedulerArgs{Mode: balancedMode, ScoringProfiles: map[string]ScoringProfile{"packing": {Mode: mostMode}}},
+			wantErrs: []string{"scoringProfiles mode Most for profile packing requires normalizeScores"},
+		},
+		{name: "ephemeral storage mode", args: CustomSchedulerArgs{Mode: mostEphemeralStorageMode, NormalizeScores: true}},
+		{name: "log curve", args: CustomSchedulerArgs{Mode: mostMode, NormalizeScores: true, ScoreCurve: logCurve}},
+		{name: "hard fail deadline", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, HardFailAfterSeconds: 60}},
+		{
+			name: "preferred node labels with weight",
+			args: CustomSchedulerArgs{
+				Mode:                      mostMode,
+				NormalizeScores:           true,
+				PreferredNodeLabels:       map[string]string{"topology.kubernetes.io/zone": "zone-a"},
+				PreferredNodeLabelsWeight: 20,
+			},
+		},
+		{name: "empty mode", args: CustomSchedulerArgs{}, wantErrs: []string{"invalid mode"}},
+		{name: "unknown mode", args: CustomSchedulerArgs{Mode: "Random"}, wantErrs: []string{"invalid mode, got Random"}},
+		{name: "unknown curve", args: CustomSchedulerArgs{Mode: leastMode, ScoreCurve: "cubic"}, wantErrs: []string{"invalid score curve"}},
+		{
+			name:     "curve with bin pack",
+			args:     CustomSchedulerArgs{Mode: binPackMode, ScoreCurve: stepCurve},
+			wantErrs: []string{"can't be combined with mode BinPack"},
+		},
+		{
+			name:     "negative hard fail deadline",
+			args:     CustomSchedulerArgs{Mode: leastMode, HardFailAfterSeconds: -1},
+			wantErrs: []string{"invalid hardFailAfterSeconds"},
+		},
+		{
+			name:     "overflowing hard fail deadline",
+			args:     CustomSchedulerArgs{Mode: leastMode, HardFailAfterSeconds: math.MaxInt64},
+			wantErrs: []string{"invalid hardFailAfterSeconds, must be at most"},
+		},
+		{
+			name:     "negative stabilization window",
+			args:     CustomSchedulerArgs{Mode: leastMode, GroupStabilizationSeconds: -5},
+			wantErrs: []string{"invalid groupStabilizationSeconds"},
+		},
+		{
+			name:     "overflowing stabilization window",
+			args:     CustomSchedulerArgs{Mode: leastMode, GroupStabilizationSeconds: math.MaxInt64},
+			wantErrs: []string{"invalid groupStabilizationSeconds, must be at most"},
+		},
+		{
+			name:     "weight without labels",
+			args:     CustomSchedulerArgs{Mode: leastMode, PreferredNodeLabelsWeight: 10},
+			wantErrs: []string{"without preferredNodeLabels"},
+		},
+		{
+			name: "invalid label key",
+			args: CustomSchedulerArgs{
+				Mode:                leastMode,
+				PreferredNodeLabels: map[string]string{"bad key": "zone-a"},
+			},
+			wantErrs: []string{"invalid preferredNodeLabels key"},
+		},
+		{name: "blend mode", args: CustomSchedulerArgs{Mode: blendMode, NormalizeScores: true, PackRatio: 0.3}},
+		{name: "pack ratio out of range", args: CustomSchedulerArgs{Mode: blendMode, PackRatio: 1.5}, wantErrs: []string{"invalid packRatio"}},
+		{name: "pack ratio without blend", args: CustomSchedulerArgs{Mode: mostMode, PackRatio: 0.5}, wantErrs: []string{"only used by mode Blend"}},
+		{name: "cost label", args: CustomSchedulerArgs{Mode: mostMode, NormalizeScores: true, CostLabel: "node.example.com/cost", CostWeight: 10}},
+		{name: "cost label without weight", args: CustomSchedulerArgs{Mode: mostMode, CostLabel: "node.example.com/cost"}, wantErrs: []string{"costWeight must be set"}},
+		{name: "cost weight without label", args: CustomSchedulerArgs{Mode: mostMode, CostWeight: 10}, wantErrs: []string{"without costLabel"}},
+		{name: "max active groups", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, MaxActiveGroups: 4}},
+		{name: "negative max active groups", args: CustomSchedulerArgs{Mode: leastMode, MaxActiveGroups: -1}, wantErrs: []string{"invalid maxActiveGroups"}},
+		{name: "negative active group ttl", args: CustomSchedulerArgs{Mode: leastMode, MaxActiveGroups: 4, ActiveGroupTTLSeconds: -1}, wantErrs: []string{"invalid activeGroupTTLSeconds"}},
+		{name: "overflowing active group ttl", args: CustomSchedulerArgs{Mode: leastMode, MaxActiveGroups: 4, ActiveGroupTTLSeconds: math.MaxInt64}, wantErrs: []string{"invalid activeGroupTTLSeconds, must be at most"}},
+		{name: "pod headroom mode", args: CustomSchedulerArgs{Mode: mostPodHeadroomMode}},
+		{
+			name:     "curve with pod headroom",
+			args:     CustomSchedulerArgs{Mode: leastPodHeadroomMode, ScoreCurve: logCurve},
+			wantErrs: []string{"can't be combined with mode LeastPodHeadroom"},
+		},
+		{name: "min free memory", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, MinFreeMemoryBytes: "2Gi"}},
+		{name: "unparsable min free memory", args: CustomSchedulerArgs{Mode: leastMode, MinFreeMemoryBytes: "lots"}, wantErrs: []string{"invalid minFreeMemoryBytes"}},
+		{name: "negative min free memory", args: CustomSchedulerArgs{Mode: leastMode, MinFreeMemoryBytes: "-1Gi"}, wantErrs: []string{"invalid minFreeMemoryBytes"}},
+		{name: "group exclude label", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, GroupExcludeLabel: "example.com/not-a-member"}},
+		{name: "invalid group exclude label", args: CustomSchedulerArgs{Mode: leastMode, GroupExcludeLabel: "not a key"}, wantErrs: []string{"invalid groupExcludeLabel"}},
+		{name: "normalize bounds", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, NormalizeMin: 0, NormalizeMax: 10}},
+		{name: "normalize bounds out of range", args: CustomSchedulerArgs{Mode: leastMode, NormalizeMin: 50, NormalizeMax: 200}, wantErrs: []string{"must be within [0,100]"}},
+		{name: "inverted normalize bounds", args: CustomSchedulerArgs{Mode: leastMode, NormalizeMin: 10, NormalizeMax: 5}, wantErrs: []string{"normalizeMin must be less than normalizeMax"}},
+		{name: "group by owner", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, GroupByOwner: true}},
+		{name: "group by owner and labels", args: CustomSchedulerArgs{Mode: leastMode, GroupByOwner: true, GroupBy: []string{"app"}}, wantErrs: []string{"can't be combined with groupByOwner"}},
+		{name: "balanced mode", args: CustomSchedulerArgs{Mode: balancedMode}},
+		{name: "curve with balanced", args: CustomSchedulerArgs{Mode: balancedMode, ScoreCurve: stepCurve}, wantErrs: []string{"can't be combined with mode Balanced"}},
+		{name: "namespace modes", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, NamespaceModes: map[string]ScoreMode{"batch": mostMode}}},
+		{name: "unknown namespace mode", args: CustomSchedulerArgs{Mode: leastMode, NamespaceModes: map[string]ScoreMode{"batch": "Pack"}}, wantErrs: []string{"invalid namespaceModes mode for namespace batch, got Pack"}},
+		{name: "annotation mode", args: CustomSchedulerArgs{Mode: mostAnnotationMode, NormalizeScores: true, AnnotationKey: "example.com/cost-score"}},
+		{name: "annotation mode without key", args: CustomSchedulerArgs{Mode: leastAnnotationMode}, wantErrs: []string{"annotationKey must be set"}},
+		{
+			name:     "namespace annotation mode without key",
+			args:     CustomSchedulerArgs{Mode: leastMode, NamespaceModes: map[string]ScoreMode{"batch": mostAnnotationMode}},
+			wantErrs: []string{"annotationKey must be set"},
+		},
+		{name: "component caps", args: CustomSchedulerArgs{Mode: mostMode, NormalizeScores: true, ComponentCaps: map[string]int64{resourceComponent: 100, costComponent: 20}, ScoreClamp: 120}},
+		{name: "unknown component cap", args: CustomSchedulerArgs{ComponentCaps: map[string]int64{"gpu": 10}}, wantErrs: []string{`invalid componentCaps component "gpu"`}},
+		{name: "non-positive component cap", args: CustomSchedulerArgs{ComponentCaps: map[string]int64{costComponent: 0}}, wantErrs: []string{"invalid componentCaps cap for cost"}},
+		{name: "negative score clamp", args: CustomSchedulerArgs{ScoreClamp: -1}, wantErrs: []string{"invalid scoreClamp"}},
+		{name: "max raw score", args: CustomSchedulerArgs{Mode: mostMode, NormalizeScores: true, MaxRawScore: 120, ScoreClamp: 120}},
+		{name: "negative max raw score", args: CustomSchedulerArgs{MaxRawScore: -1}, wantErrs: []string{"invalid maxRawScore, must be non-negative"}},
+		{name: "max raw score unlike score clamp", args: CustomSchedulerArgs{MaxRawScore: 100, ScoreClamp: 120}, wantErrs: []string{"invalid maxRawScore 100"}},
+		{name: "dominant resource", args: CustomSchedulerArgs{Mode: dominantMode, DominantPreference: mostMode}},
+		{name: "invalid dominant preference", args: CustomSchedulerArgs{Mode: dominantMode, DominantPreference: binPackMode}, wantErrs: []string{"invalid dominantPreference"}},
+		{name: "dominant preference without mode", args: CustomSchedulerArgs{Mode: leastMode, DominantPreference: mostMode}, wantErrs: []string{"only used by mode DominantResource"}},
+		{name: "curve with dominant resource", args: CustomSchedulerArgs{Mode: dominantMode, ScoreCurve: logCurve}, wantErrs: []string{"can't be combined with mode DominantResource"}},
+		{name: "explicit minimum required", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, DefaultMinAvailable: 0}},
+		{name: "negative default minAvailable", args: CustomSchedulerArgs{Mode: leastMode, DefaultMinAvailable: -1}, wantErrs: []string{"invalid defaultMinAvailable"}},
+		{name: "gpu memory resource", args: CustomSchedulerArgs{Mode: mostGPUMemoryMode, NormalizeScores: true, GPUMemoryResource: "example.com/vram"}},
+		{name: "invalid gpu memory resource", args: CustomSchedulerArgs{Mode: mostGPUMemoryMode, GPUMemoryResource: "vram/"}, wantErrs: []string{`invalid gpuMemoryResource "vram/"`}},
+		{name: "minAvailable annotation", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, MinAvailableAnnotation: "example.com/min-available"}},
+		{name: "invalid minAvailable annotation", args: CustomSchedulerArgs{Mode: leastMode, MinAvailableAnnotation: "-min"}, wantErrs: []string{`invalid minAvailableAnnotation "-min"`}},
+		{name: "cold node penalty", args: CustomSchedulerArgs{Mode: mostMode, NormalizeScores: true, ColdNodeWindowSeconds: 600, ColdNodePenaltyPercent: 50}},
+		{name: "cold node penalty over 100", args: CustomSchedulerArgs{Mode: mostMode, ColdNodeWindowSeconds: 600, ColdNodePenaltyPercent: 150}, wantErrs: []string{"invalid coldNodePenaltyPercent"}},
+		{name: "cold node window without penalty", args: CustomSchedulerArgs{Mode: mostMode, ColdNodeWindowSeconds: 600}, wantErrs: []string{"must be set together"}},
+		{name: "overflowing cold node window", args: CustomSchedulerArgs{Mode: leastMode, ColdNodeWindowSeconds: math.MaxInt64, ColdNodePenaltyPercent: 50}, wantErrs: []string{"invalid coldNodeWindowSeconds, must be at most"}},
+		{name: "permit gate phase", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, GangGatePhase: permitGatePhase}},
+		{name: "invalid gate phase", args: CustomSchedulerArgs{Mode: leastMode, GangGatePhase: "filter"}, wantErrs: []string{"invalid gangGatePhase"}},
+		{name: "permit gate phase with soft gang", args: CustomSchedulerArgs{Mode: leastMode, GangGatePhase: permitGatePhase, SoftGang: true}, wantErrs: []string{"can't be combined with softGang"}},
+		{name: "soft gang", args: CustomSchedulerArgs{Mode: binPackMode, NormalizeScores: true, SoftGang: true}},
+		{name: "soft gang without normalization", args: CustomSchedulerArgs{Mode: binPackMode, SoftGang: true}, wantErrs: []string{"softGang requires normalizeScores"}},
+		{name: "allocatable overrides", args: CustomSchedulerArgs{Mode: mostMode, NormalizeScores: true, AllocatableOverrideConfigMap: "overrides", AllocatableOverrideNamespace: "kube-system"}},
+		{name: "allocatable overrides without namespace", args: CustomSchedulerArgs{Mode: mostMode, AllocatableOverrideConfigMap: "overrides"}, wantErrs: []string{"must be set together"}},
+		{name: "invalid allocatable overrides name", args: CustomSchedulerArgs{Mode: mostMode, AllocatableOverrideConfigMap: "Overrides!", AllocatableOverrideNamespace: "kube-system"}, wantErrs: []string{`invalid allocatableOverrideConfigMap "Overrides!"`}},
+		{name: "lister breaker", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, ListerBreakerThreshold: 5, ListerBreakerWindowSeconds: 60}},
+		{name: "negative lister breaker threshold", args: CustomSchedulerArgs{Mode: leastMode, ListerBreakerThreshold: -1}, wantErrs: []string{"invalid listerBreakerThreshold"}},
+		{name: "lister breaker without window", args: CustomSchedulerArgs{Mode: leastMode, ListerBreakerThreshold: 5}, wantErrs: []string{"must be set together"}},
+		{name: "overflowing lister breaker window", args: CustomSchedulerArgs{Mode: leastMode, ListerBreakerThreshold: 5, ListerBreakerWindowSeconds: math.MaxInt64}, wantErrs: []string{"invalid listerBreakerWindowSeconds, must be at most"}},
+		{name: "free memory basis", args: CustomSchedulerArgs{Mode: mostMode, NormalizeScores: true, FreeMemoryBasis: allocatableMinusRequestedBasis}},
+		{name: "invalid free memory basis", args: CustomSchedulerArgs{Mode: mostMode, FreeMemoryBasis: "capacity"}, wantErrs: []string{"invalid freeMemoryBasis"}},
+		{name: "gang timeout", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, GangTimeoutSeconds: 600, DeleteStuckGangs: true}},
+		{name: "negative gang timeout", args: CustomSchedulerArgs{Mode: leastMode, GangTimeoutSeconds: -1}, wantErrs: []string{"invalid gangTimeoutSeconds"}},
+		{name: "overflowing gang timeout", args: CustomSchedulerArgs{Mode: leastMode, GangTimeoutSeconds: math.MaxInt64}, wantErrs: []string{"invalid gangTimeoutSeconds, must be at most"}},
+		{name: "delete stuck gangs without timeout", args: CustomSchedulerArgs{Mode: leastMode, DeleteStuckGangs: true}, wantErrs: []string{"deleteStuckGangs requires gangTimeoutSeconds"}},
+		{name: "group name normalizer", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, GroupNameNormalizer: &GroupNameNormalizer{Pattern: "^(train-job)-[0-9]+$", Replacement: "$1-*"}}},
+		{name: "group name normalizer without pattern", args: CustomSchedulerArgs{Mode: leastMode, GroupNameNormalizer: &GroupNameNormalizer{Replacement: "job"}}, wantErrs: []string{"groupNameNormalizer requires a pattern"}},
+		{name: "invalid group name normalizer", args: CustomSchedulerArgs{Mode: leastMode, GroupNameNormalizer: &GroupNameNormalizer{Pattern: "train-(job"}}, wantErrs: []string{"invalid groupNameNormalizer pattern"}},
+		{name: "group topology spread", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, GroupTopologyKey: "topology.kubernetes.io/zone", GroupMaxSkew: 2}},
+		{name: "invalid group topology key", args: CustomSchedulerArgs{Mode: leastMode, GroupTopologyKey: "zone/"}, wantErrs: []string{`invalid groupTopologyKey "zone/"`}},
+		{name: "group max skew without key", args: CustomSchedulerArgs{Mode: leastMode, GroupMaxSkew: 1}, wantErrs: []string{"groupMaxSkew requires groupTopologyKey"}},
+		{name: "group name labels", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, GroupNameLabels: []string{"nthu.io/group, podGroup"}}},
+		{name: "group name labels and group by", args: CustomSchedulerArgs{Mode: leastMode, GroupNameLabels: []string{"nthu.io/group"}, GroupBy: []string{"app"}}, wantErrs: []string{"groupNameLabels can't be combined"}},
+		{name: "invalid group name label", args: CustomSchedulerArgs{Mode: leastMode, GroupNameLabels: []string{"podGroup,bad key"}}, wantErrs: []string{"invalid groupNameLabels key"}},
+		{name: "gang backoff", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, GangBackoffSeconds: 2, MaxGangBackoffSeconds: 30}},
+		{name: "negative gang backoff", args: CustomSchedulerArgs{Mode: leastMode, GangBackoffSeconds: -1}, wantErrs: []string{"invalid gangBackoffSeconds"}},
+		{name: "overflowing gang backoff", args: CustomSchedulerArgs{Mode: leastMode, GangBackoffSeconds: math.MaxInt64, MaxGangBackoffSeconds: math.MaxInt64}, wantErrs: []string{"invalid gangBackoffSeconds, must be at most", "invalid maxGangBackoffSeconds, must be at most"}},
+		{name: "max gang backoff alone", args: CustomSchedulerArgs{Mode: leastMode, MaxGangBackoffSeconds: 30}, wantErrs: []string{"maxGangBackoffSeconds requires gangBackoffSeconds"}},
+		{name: "pressure penalty", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, PressurePenaltyPercent: 50}},
+		{name: "pressure penalty out of range", args: CustomSchedulerArgs{Mode: leastMode, PressurePenaltyPercent: 101}, wantErrs: []string{"invalid pressurePenaltyPercent"}},
+		{name: "pressure penalty and filter", args: CustomSchedulerArgs{Mode: leastMode, PressurePenaltyPercent: 50, FilterPressuredNodes: true}, wantErrs: []string{"can't be combined with filterPressuredNodes"}},
+		{name: "excluded namespaces", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, ExcludedNamespaces: []string{"kube-system", "monitoring"}}},
+		{name: "invalid excluded namespace", args: CustomSchedulerArgs{Mode: leastMode, ExcludedNamespaces: []string{"Kube_System"}}, wantErrs: []string{"invalid excludedNamespaces entry"}},
+		{name: "scoring profiles", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, ScoringProfiles: map[string]ScoringProfile{"packing": {Mode: mostMode, ScoreBy: podCapacityScoreBy}}}},
+		{name: "invalid scoring profile mode", args: CustomSchedulerArgs{Mode: leastMode, ScoringProfiles: map[string]ScoringProfile{"packing": {Mode: "Sideways"}}}, wantErrs: []string{"invalid scoringProfiles mode for profile packing"}},
+		{name: "invalid scoring profile name", args: CustomSchedulerArgs{Mode: leastMode, ScoringProfiles: map[string]ScoringProfile{"not a label": {}}}, wantErrs: []string{"invalid scoringProfiles name"}},
+		{name: "negative scoring profile weight", args: CustomSchedulerArgs{Mode: leastMode, ScoringProfiles: map[string]ScoringProfile{"cheap": {CostWeight: -1}}}, wantErrs: []string{"invalid weights for scoring profile cheap"}},
+		{name: "pod capacity", args: CustomSchedulerArgs{Mode: mostMode, NormalizeScores: true, ScoreBy: podCapacityScoreBy}},
+		{name: "unknown score by", args: CustomSchedulerArgs{Mode: mostMode, ScoreBy: "cpu"}, wantErrs: []string{"invalid scoreBy"}},
+		{name: "group by keys", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, GroupBy: []string{"app", "release"}}},
+		{
+			name:     "invalid group by key",
+			args:     CustomSchedulerArgs{Mode: leastMode, GroupBy: []string{"app", "not a key"}},
+			wantErrs: []string{"invalid groupBy key"},
+		},
+		{
+			name: "set-based group selector",
+			args: CustomSchedulerArgs{
+				Mode:            leastMode,
+				NormalizeScores: true,
+				GroupSelectorExpressions: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"gold"}},
+					},
+				},
+			},
+		},
+		{
+			name: "group selector In without values",
+			args: CustomSchedulerArgs{
+				Mode: leastMode,
+				GroupSelectorExpressions: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "tier", Operator: metav1.LabelSelectorOpIn},
+					},
+				},
+			},
+			wantErrs: []string{"invalid groupSelectorExpressions"},
+		},
+		{name: "preferred node label", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, PreferredNodeLabel: "workload-tier=batch", PreferredBonus: 50}},
+		{
+			name:     "preferred node label without value separator",
+			args:     CustomSchedulerArgs{Mode: leastMode, PreferredNodeLabel: "workload-tier", PreferredBonus: 50},
+			wantErrs: []string{"invalid preferredNodeLabel"},
+		},
+		{
+			name:     "preferred node label without bonus",
+			args:     CustomSchedulerArgs{Mode: leastMode, PreferredNodeLabel: "workload-tier=batch"},
+			wantErrs: []string{"preferredBonus must be set"},
+		},
+		{name: "count phases with empty phase", args: CustomSchedulerArgs{Mode: leastMode, NormalizeScores: true, CountPhases: []string{"", "Pending", "Running"}}},
+		{
+			name:     "unknown phase",
+			args:     CustomSchedulerArgs{Mode: leastMode, CountPhases: []string{"Pending", "Starting"}},
+			wantErrs: []string{"invalid countPhases entry, got Starting"},
+		},
+		{
+			name:     "errors are aggregated",
+			args:     CustomSchedulerArgs{Mode: "Random", ScoreCurve: "cubic", HardFailAfterSeconds: -1},
+			wantErrs: []string{"invalid mode", "invalid score curve", "invalid hardFailAfterSeconds"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.args.Validate()
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %v, got nil", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error to contain %q, got %q", want, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestCustomSchedulerArgs_ValidateSortsPreferredNodeLabels(t *testing.T) {
+	args := CustomSchedulerArgs{
+		Mode:                leastMode,
+		NormalizeScores:     true,
+		PreferredNodeLabels: map[string]string{"d key": "v", "b key": "v", "c key": "v", "a key": "v"},
+	}
+	err := args.Validate()
+	if err == nil {
+		t.Fatalf("expected errors for the invalid keys, got nil")
+	}
+	msg := err.Error()
+	last := -1
+	for _, key := range []string{"a key", "b key", "c key", "d key"} {
+		i := strings.Index(msg, fmt.Sprintf("%q", key))
+		if i < last {
+			t.Fatalf("expected the errors sorted by key, got %q", msg)
+		}
+		last = i
+	}
+}
+
+// FuzzCustomSchedulerArgs feeds raw JSON through decoding, Validate and New.
+// Run it with:
+//
+//	go test ./pkg/plugins -run '^$' -fuzz FuzzCustomSchedulerArgs
+func FuzzCustomSchedulerArgs(f *testing.F) {
+	f.Add([]byte(`{"mode": "Least"}`))
+	f.Add([]byte(`{"mode": "Most", "scoreCurve": "log", "stableTiebreak": true}`))
+	f.Add([]byte(`{"mode": "BinPack", "scoreCurve": "step"}`))
+	f.Add([]byte(`{"mode": "Least", "preferredNodeLabels": {"zone": "a"}, "preferredNodeLabelsWeight": -3}`))
+	f.Add([]byte(`{"mode": "Least", "hardFailAfterSeconds": 9223372036854775807}`))
+	f.Add([]byte(`{"mode": null, "preferredNodeLabels": null}`))
+	f.Add([]byte(`not json`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var args CustomSchedulerArgs
+		if err := json.Unmarshal(data, &args); err == nil {
+			_ = args.Validate()
+		}
+		_, _ = New(&runtime.Unknown{Raw: data}, nil)
+	})
+}
@@ -0,0 +1,127 @@
+package plugins
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// defaultMaxGangBackoff caps the backoff of a group when
+// MaxGangBackoffSeconds isn't set.
+const defaultMaxGangBackoff = 60 * time.Second
+
+// gangBackoff holds the groups rejected for being under minAvailable back for
+// a time proportional to their deficit. The scheduler of this Kubernetes
+// version takes no backoff hints from plugins, so PreFilter rejects the
+// members of a held group again without listing it, until the backoff passes
+// or a new member shows up. A nil gangBackoff never holds a group.
+type gangBackoff struct {
+	perMember time.Duration
+	max       time.Duration
+
+	mu   sync.Mutex
+	held map[string]heldGang
+}
+
+// heldGang is the rejection repeated to the members of a held group.
+type heldGang struct {
+	until   time.Time
+	message string
+}
+
+// newGangBackoff returns a backoff holding groups for perMember per missing
+// member, up to max, or nil when perMember is zero.
+func newGangBackoff(perMember, max time.Duration) *gangBackoff {
+	if perMember <= 0 {
+		return nil
+	}
+	if max <= 0 {
+		max = defaultMaxGangBackoff
+	}
+	return &gangBackoff{perMember: perMember, max: max, held: make(map[string]heldGang)}
+}
+
+// delay returns how long a group missing deficit members is held.
+func (b *gangBackoff) delay(deficit int) time.Duration {
+	d := time.Duration(deficit) * b.perMember
+	if d > b.max || d < 0 {
+		return b.max
+	}
+	return d
+}
+
+// hold holds the group from now, and returns the rejection message with the
+// backoff appended.
+func (b *gangBackoff) hold(group, message string, deficit int, now time.Time) string {
+	if b == nil {
+		return message
+	}
+	d := b.delay(deficit)
+	message = fmt.Sprintf("%s, retrying in %v", message, d)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.held[group] = heldGang{until: now.Add(d), message: message}
+	return message
+}
+
+// heldMessage returns the rejection of the group if it is held at now.
+func (b *gangBackoff) heldMessage(group string, now time.Time) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h, ok := b.held[group]
+	if !ok {
+		return "", false
+	}
+	if !now.Before(h.until) {
+		delete(b.held, group)
+		return "", false
+	}
+	return h.message, true
+}
+
+// release stops holding the group.
+func (b *gangBackoff) release(group string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.held, group)
+}
+
+// gangBackoffHandler releases the group of every pod added to it, the group
+// may have reached minAvailable. Other updates don't release it, members'
+// status updates would end most backoffs early.
+func (cs *CustomScheduler) gangBackoffHandler() cache.ResourceEventHandlerFuncs {
+	release := func(obj interface{}) {
+		if pod, ok := obj.(*v1.Pod); ok {
+			if group, ok := cs.groupName(pod); ok {
+				cs.backoff.release(group)
+			}
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: release,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, ok := oldObj.(*v1.Pod)
+			if !ok {
+				return
+			}
+			newPod, ok := newObj.(*v1.Pod)
+			if !ok {
+				return
+			}
+			// relabeled into the group
+			oldGroup, _ := cs.groupName(oldPod)
+			if newGroup, ok := cs.groupName(newPod); ok && newGroup != oldGroup {
+				cs.backoff.release(newGroup)
+			}
+		},
+	}
+}
@@ -0,0 +1,115 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func makeBackoffGroup(group string, n, minAvailable int) []*v1.Pod {
+	pods := []*v1.Pod{}
+	for i := 0; i < n; i++ {
+		pods = append(pods, st.MakePod().Name(fmt.Sprintf("%s-pod%d", group, i)).Namespace("default").
+			Label("podGroup", group).Label("minAvailable", fmt.Sprint(minAvailable)).Obj())
+	}
+	return pods
+}
+
+func TestCustomScheduler_PreFilterGangBackoff(t *testing.T) {
+	tests := []struct {
+		name         string
+		minAvailable int
+		want         string
+	}{
+		{name: "one member missing", minAvailable: 3, want: "retrying in 2s"},
+		{name: "three members missing", minAvailable: 5, want: "retrying in 6s"},
+		{name: "capped", minAvailable: 100, want: "retrying in 1m0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pods := makeBackoffGroup("g1", 2, tt.minAvailable)
+			fh := newTestHandle(t, nil, pods)
+			cs := &CustomScheduler{
+				handle:    fh,
+				scoreMode: leastMode,
+				clock:     testingclock.NewFakeClock(time.Now()),
+				backoff:   newGangBackoff(2*time.Second, 0),
+			}
+			_, status := cs.PreFilter(context.Background(), nil, pods[0])
+			if status.Code() != framework.Unschedulable {
+				t.Fatalf("expected Unschedulable, got %v", status)
+			}
+			if !strings.HasSuffix(status.Message(), tt.want) {
+				t.Errorf("expected the message to end with %q, got %q", tt.want, status.Message())
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterGangBackoffHolds(t *testing.T) {
+	pods := makeBackoffGroup("g1", 3, 3)
+	fh := newTestHandle(t, nil, pods[:2])
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	cs := &CustomScheduler{
+		handle:    fh,
+		scoreMode: leastMode,
+		clock:     fakeClock,
+		backoff:   newGangBackoff(2*time.Second, 0),
+	}
+	if _, status := cs.PreFilter(context.Background(), nil, pods[0]); status.Code() != framework.Unschedulable {
+		t.Fatalf("expected Unschedulable, got %v", status)
+	}
+
+	// the last member is listed, but the group isn't rechecked until the
+	// backoff passes
+	store := fh.SharedInformerFactory().Core().V1().Pods().Informer().GetStore()
+	if err := store.Add(pods[2]); err != nil {
+		t.Fatalf("fail to add pod: %s", err)
+	}
+	if _, status := cs.PreFilter(context.Background(), nil, pods[1]); status.Code() != framework.Unschedulable {
+		t.Errorf("expected the held group rejected, got %v", status)
+	}
+	fakeClock.Step(2 * time.Second)
+	if _, status := cs.PreFilter(context.Background(), nil, pods[1]); !status.IsSuccess() {
+		t.Errorf("expected the group rechecked after the backoff, got %v", status)
+	}
+}
+
+func TestCustomScheduler_GangBackoffReleasedOnNewMember(t *testing.T) {
+	pods := makeBackoffGroup("g1", 3, 3)
+	fh := newTestHandle(t, nil, pods[:2])
+	cs := &CustomScheduler{
+		handle:    fh,
+		scoreMode: leastMode,
+		clock:     testingclock.NewFakeClock(time.Now()),
+		backoff:   newGangBackoff(time.Minute, 0),
+	}
+	if _, status := cs.PreFilter(context.Background(), nil, pods[0]); status.Code() != framework.Unschedulable {
+		t.Fatalf("expected Unschedulable, got %v", status)
+	}
+
+	// an update of a member that stays in the group doesn't end the backoff
+	handler := cs.gangBackoffHandler()
+	updated := pods[1].DeepCopy()
+	updated.Status.Phase = v1.PodPending
+	handler.OnUpdate(pods[1], updated)
+	if _, held := cs.backoff.heldMessage("g1", cs.now()); !held {
+		t.Fatalf("expected the group still held after a member's update")
+	}
+
+	store := fh.SharedInformerFactory().Core().V1().Pods().Informer().GetStore()
+	if err := store.Add(pods[2]); err != nil {
+		t.Fatalf("fail to add pod: %s", err)
+	}
+	handler.OnAdd(pods[2], false)
+	if _, status := cs.PreFilter(context.Background(), nil, pods[1]); !status.IsSuccess() {
+		t.Errorf("expected the group rechecked once a member joined, got %v", status)
+	}
+}
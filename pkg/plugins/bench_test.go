@@ -0,0 +1,131 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+// The benchmarks give baselines for the plugin's hot paths on synthetic
+// clusters. Run them without the unit tests and with allocations reported:
+//
+//	go test ./pkg/plugins -run '^$' -bench . -benchmem
+//
+// The fake snapshot looks nodes up linearly, so Score includes that lookup.
+
+var benchmarkSizes = []int{100, 1000, 5000, 10000}
+
+// benchmarkGroupSize is the number of members in each synthetic group.
+const benchmarkGroupSize = 10
+
+func benchmarkNodes(n int) []*framework.NodeInfo {
+	nodes := make([]*framework.NodeInfo, 0, n)
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, makeNodeInfo(fmt.Sprintf("node%d", i), 4000, int64(i+1)<<30))
+	}
+	return nodes
+}
+
+func benchmarkPods(n int) []*v1.Pod {
+	pods := make([]*v1.Pod, 0, n)
+	for i := 0; i < n; i++ {
+		pods = append(pods, st.MakePod().Name(fmt.Sprintf("pod%d", i)).Namespace("default").
+			Label("podGroup", fmt.Sprintf("group%d", i/benchmarkGroupSize)).
+			Label("minAvailable", fmt.Sprint(benchmarkGroupSize)).Obj())
+	}
+	return pods
+}
+
+func BenchmarkPreFilter(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%d pods", n), func(b *testing.B) {
+			pods := benchmarkPods(n)
+			fh := newTestHandle(b, benchmarkNodes(n), pods)
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, status := cs.PreFilter(context.Background(), nil, pods[i%n]); !status.IsSuccess() {
+					b.Fatalf("unexpected status: %v", status)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPreFilterLargeGroup puts every pod in one group, so each PreFilter
+// lists and counts all of them.
+func BenchmarkPreFilterLargeGroup(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%d members", n), func(b *testing.B) {
+			pods := make([]*v1.Pod, 0, n)
+			for i := 0; i < n; i++ {
+				pods = append(pods, st.MakePod().Name(fmt.Sprintf("pod%d", i)).Namespace("default").
+					Label("podGroup", "large").Label("minAvailable", fmt.Sprint(n)).Obj())
+			}
+			fh := newTestHandle(b, benchmarkNodes(benchmarkGroupSize), pods)
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, status := cs.PreFilter(context.Background(), nil, pods[i%n]); !status.IsSuccess() {
+					b.Fatalf("unexpected status: %v", status)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkScore(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%d nodes", n), func(b *testing.B) {
+			nodes := benchmarkNodes(n)
+			fh := newTestHandle(b, nodes, nil)
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode}
+			pod := benchmarkPods(1)[0]
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, status := cs.Score(context.Background(), nil, pod, nodes[i%n].Node().Name); !status.IsSuccess() {
+					b.Fatalf("unexpected status: %v", status)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkNormalizeScore compares the inline pass, which is what a plugin
+// without a handle gets, with the parallelizer's chunks. The parallel runs
+// only pull ahead with more than one CPU, so set -cpu when comparing.
+func BenchmarkNormalizeScore(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		raw := make(framework.NodeScoreList, 0, n)
+		for i := 0; i < n; i++ {
+			raw = append(raw, framework.NodeScore{Name: fmt.Sprintf("node%d", i), Score: -int64(i+1) << 30})
+		}
+		for _, parallel := range []bool{false, true} {
+			name := fmt.Sprintf("%d nodes serial", n)
+			cs := &CustomScheduler{scoreMode: leastMode}
+			if parallel {
+				name = fmt.Sprintf("%d nodes parallel", n)
+				cs.handle = newTestHandle(b, nil, nil)
+			}
+			b.Run(name, func(b *testing.B) {
+				scores := make(framework.NodeScoreList, n)
+				pod := benchmarkPods(1)[0]
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					copy(scores, raw)
+					if status := cs.NormalizeScore(context.Background(), nil, pod, scores); !status.IsSuccess() {
+						b.Fatalf("unexpected status: %v", status)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package plugins
+
+import (
+	"sync"
+	"time"
+)
+
+// listerBreaker turns gang gating off for a while once listing the members
+// of groups failed threshold times in a row within the window, so a broken
+// lister doesn't stall every grouped pod in the cluster. The lister is tried
+// again once the window passes. A nil breaker never opens.
+type listerBreaker struct {
+	threshold int
+	window    time.Duration
+
+	mu           sync.Mutex
+	failures     int
+	firstFailure time.Time
+	openUntil    time.Time
+}
+
+// newListerBreaker returns a breaker opening after threshold consecutive
+// failures within window, or nil when threshold is zero.
+func newListerBreaker(threshold int, window time.Duration) *listerBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &listerBreaker{threshold: threshold, window: window}
+}
+
+// isOpen reports whether gang gating is off at now.
+func (b *listerBreaker) isOpen(now time.Time) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Before(b.openUntil)
+}
+
+// failure records a lister error at now and reports whether it opened the
+// breaker.
+func (b *listerBreaker) failure(now time.Time) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// failures further apart than the window don't add up
+	if b.failures == 0 || now.Sub(b.firstFailure) > b.window {
+		b.failures = 0
+		b.firstFailure = now
+	}
+	b.failures++
+	if b.failures < b.threshold {
+		return false
+	}
+	b.failures = 0
+	b.openUntil = now.Add(b.window)
+	return true
+}
+
+// success records a lister call that went through.
+func (b *listerBreaker) success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
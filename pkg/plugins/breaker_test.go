@@ -0,0 +1,108 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestCustomScheduler_PreFilterListerBreaker(t *testing.T) {
+	pod := st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "3").Obj()
+	fh := newTestHandle(t, nil, []*v1.Pod{pod})
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := testingclock.NewFakeClock(now)
+	counter := &fakeGroupCounter{err: errors.New("remote unavailable")}
+	cs := &CustomScheduler{
+		handle:       fh,
+		clock:        fakeClock,
+		scoreMode:    leastMode,
+		groupCounter: counter,
+		breaker:      newListerBreaker(3, time.Minute),
+	}
+	preFilter := func() *framework.Status {
+		_, status := cs.PreFilter(context.Background(), nil, pod)
+		return status
+	}
+
+	for i := 0; i < 3; i++ {
+		if got := preFilter(); got.Code() != framework.Error {
+			t.Fatalf("expected lister error %d to fail the pod, got %v", i, got)
+		}
+	}
+	if got := preFilter(); !got.IsSuccess() {
+		t.Errorf("expected the open breaker to let the pod through, got %v", got)
+	}
+
+	// the lister is tried again after the window
+	fakeClock.Step(time.Minute)
+	if got := preFilter(); got.Code() != framework.Error {
+		t.Errorf("expected the lister retried once the window passed, got %v", got)
+	}
+
+	// a successful count resets the streak
+	counter.err = nil
+	if got := preFilter(); got.Code() != framework.Unschedulable {
+		t.Errorf("expected the gang gated again, got %v", got)
+	}
+	counter.err = errors.New("remote unavailable")
+	for i := 0; i < 2; i++ {
+		preFilter()
+	}
+	if got := preFilter(); got.Code() != framework.Error {
+		t.Errorf("expected the breaker closed before %d consecutive errors, got %v", 3, got)
+	}
+}
+
+func TestCustomScheduler_PreBindListerBreaker(t *testing.T) {
+	pod := st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "3").Obj()
+	fh := newTestHandle(t, []*framework.NodeInfo{makeNodeInfo("m1", 1000, 200)}, []*v1.Pod{pod})
+	cs := &CustomScheduler{
+		handle:       fh,
+		clock:        testingclock.NewFakeClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+		scoreMode:    leastMode,
+		groupCounter: &fakeGroupCounter{err: errors.New("remote down")},
+		breaker:      newListerBreaker(2, time.Minute),
+	}
+
+	// PreBind's lister errors count toward opening the breaker
+	if got := cs.PreBind(context.Background(), nil, pod, "m1"); got.Code() != framework.Error {
+		t.Fatalf("expected the lister error to fail the pod, got %v", got)
+	}
+	if _, got := cs.PreFilter(context.Background(), nil, pod); got.Code() != framework.Error {
+		t.Fatalf("expected the lister error to fail the pod, got %v", got)
+	}
+	if _, got := cs.PreFilter(context.Background(), nil, pod); !got.IsSuccess() {
+		t.Fatalf("expected the open breaker to let the pod through, got %v", got)
+	}
+	// the pod let through isn't recounted before binding
+	if got := cs.PreBind(context.Background(), nil, pod, "m1"); !got.IsSuccess() {
+		t.Errorf("expected PreBind to pass while the breaker is open, got %v", got)
+	}
+}
+
+func TestListerBreaker_SpreadFailures(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newListerBreaker(2, time.Minute)
+	if b.failure(now) {
+		t.Fatalf("expected the first failure to keep the breaker closed")
+	}
+	// failures further apart than the window don't add up
+	if b.failure(now.Add(2 * time.Minute)) {
+		t.Errorf("expected failures outside the window to keep the breaker closed")
+	}
+	if !b.failure(now.Add(2*time.Minute + time.Second)) {
+		t.Errorf("expected the second failure within the window to open the breaker")
+	}
+	if !b.isOpen(now.Add(2*time.Minute + 30*time.Second)) {
+		t.Errorf("expected the breaker open within the window")
+	}
+	if newListerBreaker(0, time.Minute).isOpen(now) {
+		t.Errorf("expected a disabled breaker to stay closed")
+	}
+}
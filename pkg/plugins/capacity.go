@@ -0,0 +1,91 @@
+package plugins
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// checkGangCapacity rejects the gang early when the cluster can't fit the
+// members still to be placed, assuming they all request as much as the pod.
+// It is a best-effort check against the snapshot: it only counts ready,
+// uncordoned nodes and ignores every other predicate, so a gang that passes
+// may still not fit.
+func (cs *CustomScheduler) checkGangCapacity(pod *v1.Pod, group string, pods []*v1.Pod, minAvailable int) *framework.Status {
+	need := minAvailable
+	for _, p := range pods {
+		if p.Spec.NodeName != "" {
+			need--
+		}
+	}
+	cpu, memory := podCPURequest(pod), podMemoryRequest(pod)
+	if need <= 0 || (cpu == 0 && memory == 0) {
+		return nil
+	}
+
+	// the snapshot may not be wired up yet while the scheduler warms up
+	lister := cs.handle.SnapshotSharedLister()
+	if lister == nil {
+		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Group %s can't be checked for capacity, the snapshot lister is unavailable", group))
+	}
+	nodeInfos, err := lister.NodeInfos().List()
+	if err != nil {
+		return framework.AsStatus(fmt.Errorf("error listing nodes: %v", err))
+	}
+	fits := 0
+	for _, ni := range nodeInfos {
+		if ni.Node() == nil || ni.Allocatable == nil || ni.Requested == nil || readinessRejection(ni.Node()) != nil {
+			continue
+		}
+		fits += copiesFitting(ni.Allocatable.MilliCPU-ni.Requested.MilliCPU, cpu, memoryScore(ni)-ni.Requested.Memory, memory)
+		if fits >= need {
+			return nil
+		}
+	}
+	return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Group %s needs room for %d more pods, the cluster fits %d", group, need, fits))
+}
+
+// copiesFitting returns how many pods with the given requests fit in the
+// free CPU and memory of a node. A zero request doesn't limit the count.
+func copiesFitting(freeCPU, cpu, freeMemory, memory int64) int {
+	if freeCPU < 0 || freeMemory < 0 {
+		return 0
+	}
+	copies := int64(-1)
+	if cpu > 0 {
+		copies = freeCPU / cpu
+	}
+	if memory > 0 && (copies < 0 || freeMemory/memory < copies) {
+		copies = freeMemory / memory
+	}
+	return int(copies)
+}
+
+// checkPodMemoryFits rejects the pod for good when it requests more memory
+// than the largest node in the snapshot allocates, it would never schedule.
+// Nodes that don't report their resources yet are ignored, and so is an
+// empty snapshot.
+func (cs *CustomScheduler) checkPodMemoryFits(pod *v1.Pod) *framework.Status {
+	memory := podMemoryRequest(pod)
+	lister := cs.handle.SnapshotSharedLister()
+	if memory == 0 || lister == nil {
+		return nil
+	}
+	nodeInfos, err := lister.NodeInfos().List()
+	if err != nil {
+		return framework.AsStatus(fmt.Errorf("error listing nodes: %v", err))
+	}
+	largest := int64(-1)
+	for _, ni := range nodeInfos {
+		if ni.Allocatable != nil && memoryScore(ni) > largest {
+			largest = memoryScore(ni)
+		}
+	}
+	if largest < 0 || memory <= largest {
+		return nil
+	}
+	return framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("Pod %s requests %s memory, more than the largest node allocates (%s)",
+		pod.Name, resource.NewQuantity(memory, resource.BinarySI), resource.NewQuantity(largest, resource.BinarySI)))
+}
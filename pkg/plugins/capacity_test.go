@@ -0,0 +1,139 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+func TestCustomScheduler_PreFilterCapacityCheck(t *testing.T) {
+	const gi = int64(1 << 30)
+	cordoned := makeNodeInfo("cordoned", 4000, 8*gi)
+	cordoned.Node().Spec.Unschedulable = true
+	// each ready node fits two members, on CPU and memory alike
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 2*gi),
+		makeNodeInfo("m2", 1000, 2*gi),
+		cordoned,
+	}
+	group := func(size, minAvailable int) []*v1.Pod {
+		pods := []*v1.Pod{}
+		for i := 0; i < size; i++ {
+			pods = append(pods, st.MakePod().Name(fmt.Sprintf("pod%d", i)).Namespace("default").
+				Label("podGroup", "g1").Label("minAvailable", fmt.Sprint(minAvailable)).
+				Req(map[v1.ResourceName]string{v1.ResourceCPU: "500m", v1.ResourceMemory: "1Gi"}).Obj())
+		}
+		return pods
+	}
+
+	tests := []struct {
+		name string
+		pods []*v1.Pod
+		want *framework.Status
+	}{
+		{name: "gang fits", pods: group(4, 4), want: framework.NewStatus(framework.Success, "")},
+		{
+			name: "cluster too small for the gang",
+			pods: group(5, 5),
+			want: framework.NewStatus(framework.Unschedulable, "Group g1 needs room for 5 more pods, the cluster fits 4"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, nodeInfos, tt.pods)
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode, capacityCheck: true}
+			_, got := cs.PreFilter(context.Background(), nil, tt.pods[0])
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// noSnapshotHandle is a handle whose informers are wired up but whose
+// snapshot lister isn't yet.
+type noSnapshotHandle struct {
+	framework.Handle
+}
+
+func (noSnapshotHandle) SnapshotSharedLister() framework.SharedLister {
+	return nil
+}
+
+func TestCustomScheduler_PreFilterCapacityCheckNoSnapshot(t *testing.T) {
+	pods := []*v1.Pod{}
+	for i := 0; i < 2; i++ {
+		pods = append(pods, st.MakePod().Name(fmt.Sprintf("pod%d", i)).Namespace("default").
+			Label("podGroup", "g1").Label("minAvailable", "2").
+			Req(map[v1.ResourceName]string{v1.ResourceCPU: "500m", v1.ResourceMemory: "1Gi"}).Obj())
+	}
+	fh := noSnapshotHandle{Handle: newTestHandle(t, nil, pods)}
+	cs := &CustomScheduler{handle: fh, scoreMode: leastMode, capacityCheck: true}
+	_, status := cs.PreFilter(context.Background(), nil, pods[0])
+	if status.Code() != framework.Unschedulable {
+		t.Errorf("expected a retriable rejection without a snapshot lister, got %v", status)
+	}
+}
+
+func TestCopiesFitting(t *testing.T) {
+	tests := []struct {
+		name                             string
+		freeCPU, cpu, freeMemory, memory int64
+		want                             int
+	}{
+		{name: "memory bound", freeCPU: 4000, cpu: 500, freeMemory: 3, memory: 1, want: 3},
+		{name: "cpu bound", freeCPU: 1000, cpu: 500, freeMemory: 10, memory: 1, want: 2},
+		{name: "no cpu request", freeCPU: 0, cpu: 0, freeMemory: 4, memory: 2, want: 2},
+		{name: "overcommitted node", freeCPU: -100, cpu: 500, freeMemory: 4, memory: 1, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := copiesFitting(tt.freeCPU, tt.cpu, tt.freeMemory, tt.memory); got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterOversizedPod(t *testing.T) {
+	const gi = int64(1 << 30)
+	registering := makeNodeInfo("registering", 0, 0)
+	registering.Allocatable = nil
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 4000, 8*gi),
+		makeNodeInfo("m2", 4000, 16*gi),
+		registering,
+	}
+	member := func(memory string) *v1.Pod {
+		return st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "1").
+			Req(map[v1.ResourceName]string{v1.ResourceMemory: memory}).Obj()
+	}
+
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+		want *framework.Status
+	}{
+		{name: "fits the largest node", pod: member("16Gi"), want: framework.NewStatus(framework.Success, "")},
+		{
+			name: "larger than every node",
+			pod:  member("32Gi"),
+			want: framework.NewStatus(framework.UnschedulableAndUnresolvable, "Pod pod0 requests 32Gi memory, more than the largest node allocates (16Gi)"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, nodeInfos, []*v1.Pod{tt.pod})
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode}
+			_, got := cs.PreFilter(context.Background(), nil, tt.pod)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
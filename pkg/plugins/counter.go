@@ -0,0 +1,58 @@
+package plugins
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+// GroupCounter counts the members of a group from a source other than the
+// scheduler's own pod lister, e.g. every cluster a gang spans.
+type GroupCounter interface {
+	// Count returns the number of members the group has in the namespace.
+	Count(namespace, group string) (int, error)
+}
+
+// NewWithGroupCounter returns a plugin factory like New whose plugins check
+// minAvailable against the counter instead of the local pods. Register it in
+// place of New, e.g. app.WithPlugin(plugins.Name, plugins.NewWithGroupCounter(counter)).
+func NewWithGroupCounter(counter GroupCounter) frameworkruntime.PluginFactory {
+	return func(obj runtime.Object, h framework.Handle) (framework.Plugin, error) {
+		p, err := New(obj, h)
+		if err != nil {
+			return nil, err
+		}
+		p.(*CustomScheduler).groupCounter = counter
+		return p, nil
+	}
+}
+
+// groupCount returns the number of members counted toward the group's
+// minAvailable, from the group counter when one is configured.
+func (cs *CustomScheduler) groupCount(pod *v1.Pod, group string, pods []*v1.Pod) (int, error) {
+	if cs.groupCounter == nil {
+		if cs.minAvailableByPriority {
+			pods = atOrAbovePriority(pods, podPriority(pod))
+		}
+		return cs.countMembers(pods), nil
+	}
+	count, err := cs.groupCounter.Count(pod.Namespace, group)
+	if err != nil {
+		return 0, fmt.Errorf("error counting members of group %s: %v", group, err)
+	}
+	return count, nil
+}
+
+// atOrAbovePriority returns the pods whose priority is at least priority.
+func atOrAbovePriority(pods []*v1.Pod, priority int32) []*v1.Pod {
+	var kept []*v1.Pod
+	for _, p := range pods {
+		if podPriority(p) >= priority {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,74 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+// fakeGroupCounter counts groups from a fixed table keyed by namespace/group.
+type fakeGroupCounter struct {
+	counts map[string]int
+	err    error
+}
+
+func (c *fakeGroupCounter) Count(namespace, group string) (int, error) {
+	return c.counts[namespace+"/"+group], c.err
+}
+
+func TestCustomScheduler_PreFilterGroupCounter(t *testing.T) {
+	pods := []*v1.Pod{
+		st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "3").Obj(),
+	}
+	fh := newTestHandle(t, nil, pods)
+
+	tests := []struct {
+		name    string
+		counter GroupCounter
+		want    *framework.Status
+	}{
+		{
+			name: "local lister",
+			want: framework.NewStatus(framework.Unschedulable, "Not enough pods in group g1, 1 present, minimum required is 3"),
+		},
+		{
+			name:    "members in other clusters count",
+			counter: &fakeGroupCounter{counts: map[string]int{"default/g1": 3}},
+			want:    framework.NewStatus(framework.Success, ""),
+		},
+		{
+			name:    "external count below minimum",
+			counter: &fakeGroupCounter{counts: map[string]int{"default/g1": 2}},
+			want:    framework.NewStatus(framework.Unschedulable, "Not enough pods in group g1, 2 present, minimum required is 3"),
+		},
+		{
+			name:    "counter error",
+			counter: &fakeGroupCounter{err: errors.New("remote unavailable")},
+			want:    framework.AsStatus(errors.New("error counting members of group g1: remote unavailable")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode, groupCounter: tt.counter}
+			_, got := cs.PreFilter(context.Background(), nil, pods[0])
+			if got.Code() != tt.want.Code() || got.Message() != tt.want.Message() {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewWithGroupCounter(t *testing.T) {
+	counter := &fakeGroupCounter{}
+	p, err := NewWithGroupCounter(counter)(nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := p.(*CustomScheduler).groupCounter; got != GroupCounter(counter) {
+		t.Errorf("expected the counter to be configured, got %v", got)
+	}
+}
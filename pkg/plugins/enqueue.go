@@ -0,0 +1,23 @@
+package plugins
+
+import (
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ framework.EnqueueExtensions = &CustomScheduler{}
+
+// EventsToRegister returns the cluster events that can make a pod rejected by
+// this plugin schedulable: a new or updated pod may complete its group, a
+// deleted pod frees memory, and a new node adds capacity. The scheduling
+// framework of this Kubernetes version has no per-event queueing hints, so the
+// events can't be narrowed to the rejected pod's namespace. Nodes filtered out
+// as cordoned or NotReady come back on uncordon and condition changes.
+func (cs *CustomScheduler) EventsToRegister() []framework.ClusterEvent {
+	return []framework.ClusterEvent{
+		{Resource: framework.Pod, ActionType: framework.Add | framework.Update | framework.Delete},
+		{
+			Resource:   framework.Node,
+			ActionType: framework.Add | framework.UpdateNodeTaint | framework.UpdateNodeCondition,
+		},
+	}
+}
@@ -0,0 +1,19 @@
+package plugins
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestCustomScheduler_EventsToRegister(t *testing.T) {
+	cs := &CustomScheduler{}
+	want := []framework.ClusterEvent{
+		{Resource: framework.Pod, ActionType: framework.Add | framework.Update | framework.Delete},
+		{Resource: framework.Node, ActionType: framework.Add | framework.UpdateNodeTaint | framework.UpdateNodeCondition},
+	}
+	if got := cs.EventsToRegister(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
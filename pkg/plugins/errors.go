@@ -0,0 +1,45 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors reported by New and PreFilter, wrapped with the offending pod or
+// value. Match them with errors.Is.
+var (
+	// ErrInvalidMode is reported for modes the plugin doesn't know.
+	ErrInvalidMode = errors.New("invalid mode")
+	// ErrGroupLabelMissing is reported for pods without a group label when
+	// ungrouped pods aren't skipped.
+	ErrGroupLabelMissing = errors.New("group label not found")
+	// ErrGroupOwnerMissing is the ErrGroupLabelMissing of groups by
+	// controller owner.
+	ErrGroupOwnerMissing = errors.New("controller owner not found")
+	// ErrMinAvailableMissing is reported for grouped pods without a
+	// minimum when the minimum must be explicit.
+	ErrMinAvailableMissing = errors.New("group minAvail not found")
+	// ErrInvalidMinAvailable is reported for minimums that aren't positive
+	// integers.
+	ErrInvalidMinAvailable = errors.New("minAvailable must be a positive integer")
+)
+
+// sentinelError is an error with its own message that matches a sentinel,
+// for messages that don't start with the sentinel's.
+type sentinelError struct {
+	msg      string
+	sentinel error
+}
+
+func (e *sentinelError) Error() string {
+	return e.msg
+}
+
+func (e *sentinelError) Unwrap() error {
+	return e.sentinel
+}
+
+// errorWithSentinel formats an error matching the sentinel.
+func errorWithSentinel(sentinel error, format string, args ...interface{}) error {
+	return &sentinelError{msg: fmt.Sprintf(format, args...), sentinel: sentinel}
+}
@@ -0,0 +1,77 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+func TestNew_ErrorsIs(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		want error
+	}{
+		{name: "invalid mode", args: `{"mode": "Spread"}`, want: ErrInvalidMode},
+		{name: "invalid namespace mode", args: `{"mode": "Least", "namespaceModes": {"batch": "Spread"}}`, want: ErrInvalidMode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&runtime.Unknown{Raw: []byte(tt.args)}, nil)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected an error matching %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := ParseMode("Spread"); !errors.Is(err, ErrInvalidMode) {
+		t.Errorf("expected ParseMode to report %q, got %v", ErrInvalidMode, err)
+	}
+}
+
+func TestCustomScheduler_PreFilterErrorsIs(t *testing.T) {
+	tests := []struct {
+		name string
+		cs   *CustomScheduler
+		pod  *v1.Pod
+		want error
+	}{
+		{
+			name: "group label missing",
+			cs:   &CustomScheduler{scoreMode: leastMode},
+			pod:  st.MakePod().Name("pod0").Namespace("default").Obj(),
+			want: ErrGroupLabelMissing,
+		},
+		{
+			name: "controller owner missing",
+			cs:   &CustomScheduler{scoreMode: leastMode, groupByOwner: true},
+			pod:  st.MakePod().Name("pod0").Namespace("default").Obj(),
+			want: ErrGroupOwnerMissing,
+		},
+		{
+			name: "minAvailable missing",
+			cs:   &CustomScheduler{scoreMode: leastMode},
+			pod:  st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "g1").Obj(),
+			want: ErrMinAvailableMissing,
+		},
+		{
+			name: "invalid minAvailable",
+			cs:   &CustomScheduler{scoreMode: leastMode},
+			pod:  st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "0").Obj(),
+			want: ErrInvalidMinAvailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cs.handle = newTestHandle(t, nil, []*v1.Pod{tt.pod})
+			_, status := tt.cs.PreFilter(context.Background(), nil, tt.pod)
+			if !errors.Is(status.AsError(), tt.want) {
+				t.Errorf("expected an error matching %q, got %v", tt.want, status.AsError())
+			}
+		})
+	}
+}
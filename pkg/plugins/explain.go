@@ -0,0 +1,92 @@
+package plugins
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const explanationAnnotation string = "customscheduler.example.com/explanation"
+
+// explanationStateKey is the CycleState key of the per-node score
+// explanations.
+const explanationStateKey = framework.StateKey(Name + "/explanation")
+
+// maxExplanationLength bounds the explanation annotation, longer ones are
+// truncated.
+const maxExplanationLength = 1024
+
+var _ framework.PreScorePlugin = &CustomScheduler{}
+
+// scoreExplanations holds a human-readable account of how each node was
+// scored in the current cycle. Score runs for several nodes in parallel, so
+// access goes through the mutex.
+type scoreExplanations struct {
+	mu    sync.Mutex
+	nodes map[string]string
+}
+
+// Clone implements framework.StateData.
+func (e *scoreExplanations) Clone() framework.StateData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	nodes := make(map[string]string, len(e.nodes))
+	for k, v := range e.nodes {
+		nodes[k] = v
+	}
+	return &scoreExplanations{nodes: nodes}
+}
+
+// PreScore prepares the CycleState the Score calls record their explanations
+// and raw scores in, along with how the pod is scored for NormalizeScore.
+func (cs *CustomScheduler) PreScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodes []*v1.Node) *framework.Status {
+	state.Write(explanationStateKey, &scoreExplanations{nodes: make(map[string]string, len(nodes))})
+	state.Write(ScoresStateKey, &NodeScores{scores: make(map[string]int64, len(nodes))})
+	state.Write(scoreStateKey, cs.newScoreState(pod))
+	return nil
+}
+
+// recordExplanation stores the notes on how the node was scored. It does
+// nothing when PreScore didn't run, e.g. in tests calling Score directly.
+func recordExplanation(state *framework.CycleState, nodeName string, notes []string) {
+	if state == nil {
+		return
+	}
+	data, err := state.Read(explanationStateKey)
+	if err != nil {
+		return
+	}
+	e, ok := data.(*scoreExplanations)
+	if !ok {
+		return
+	}
+	text := strings.Join(notes, ", ")
+	if len(text) > maxExplanationLength {
+		text = text[:maxExplanationLength-3] + "..."
+	}
+	e.mu.Lock()
+	e.nodes[nodeName] = text
+	e.mu.Unlock()
+}
+
+// explanationFor returns the explanation recorded for the node.
+func explanationFor(state *framework.CycleState, nodeName string) (string, bool) {
+	if state == nil {
+		return "", false
+	}
+	data, err := state.Read(explanationStateKey)
+	if err != nil {
+		return "", false
+	}
+	e, ok := data.(*scoreExplanations)
+	if !ok {
+		return "", false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	text, ok := e.nodes[nodeName]
+	return text, ok
+}
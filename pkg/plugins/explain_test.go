@@ -0,0 +1,59 @@
+package plugins
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestCustomScheduler_PreBindExplanation(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 200),
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod0", Namespace: "default"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{}},
+	}
+	fh := newTestHandle(t, nodeInfos, []*v1.Pod{pod})
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode, scoreBy: memoryScoreBy}
+
+	state := framework.NewCycleState()
+	if status := cs.PreScore(context.Background(), state, pod, []*v1.Node{nodeInfos[0].Node(), nodeInfos[1].Node()}); !status.IsSuccess() {
+		t.Fatalf("unexpected PreScore error: %v", status)
+	}
+	scoreNodes(t, cs, state, pod, nodeInfos)
+	if status := cs.PreBind(context.Background(), state, pod, "m2"); !status.IsSuccess() {
+		t.Fatalf("unexpected PreBind error: %v", status)
+	}
+
+	got, err := fh.ClientSet().CoreV1().Pods("default").Get(context.Background(), "pod0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("fail to get pod: %s", err)
+	}
+	explanation := got.Annotations[explanationAnnotation]
+	for _, want := range []string{"mode Most", "memory 200"} {
+		if !strings.Contains(explanation, want) {
+			t.Errorf("expected explanation to contain %q, got %q", want, explanation)
+		}
+	}
+}
+
+func TestRecordExplanationTruncates(t *testing.T) {
+	state := framework.NewCycleState()
+	state.Write(explanationStateKey, &scoreExplanations{nodes: map[string]string{}})
+
+	recordExplanation(state, "m1", []string{strings.Repeat("x", 2*maxExplanationLength)})
+
+	got, ok := explanationFor(state, "m1")
+	if !ok {
+		t.Fatalf("expected an explanation for node m1")
+	}
+	if len(got) != maxExplanationLength || !strings.HasSuffix(got, "...") {
+		t.Errorf("expected explanation truncated to %d bytes, got %d", maxExplanationLength, len(got))
+	}
+}
@@ -0,0 +1,135 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+// newTestHandle builds a framework.Handle for unit tests without an API
+// server. The snapshot serves the given nodes, and the given pods are both in
+// the fake clientset and in the pods informer cache, so the lister sees them
+// without starting the informer. Extra options, e.g. an event recorder, are
+// passed on to the framework.
+func newTestHandle(t testing.TB, nodes []*framework.NodeInfo, pods []*v1.Pod, opts ...frameworkruntime.Option) framework.Handle {
+	t.Helper()
+	return newTestFramework(t, nil, nodes, pods, opts...)
+}
+
+// newTestFramework is newTestHandle with extra plugins registered, for tests
+// running the framework's own extension points.
+func newTestFramework(t testing.TB, plugins []st.RegisterPluginFunc, nodes []*framework.NodeInfo, pods []*v1.Pod, opts ...frameworkruntime.Option) framework.Framework {
+	t.Helper()
+	objs := make([]runtime.Object, 0, len(pods))
+	for _, p := range pods {
+		objs = append(objs, p)
+	}
+	client := clientsetfake.NewSimpleClientset(objs...)
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	store := informerFactory.Core().V1().Pods().Informer().GetStore()
+	for _, p := range pods {
+		if err := store.Add(p); err != nil {
+			t.Fatalf("fail to add pod %s: %s", p.Name, err)
+		}
+	}
+
+	registeredPlugins := []st.RegisterPluginFunc{
+		st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}
+	registeredPlugins = append(registeredPlugins, plugins...)
+	opts = append([]frameworkruntime.Option{
+		frameworkruntime.WithClientSet(client),
+		frameworkruntime.WithInformerFactory(informerFactory),
+		frameworkruntime.WithSnapshotSharedLister(&fakeSharedLister{nodes: nodes}),
+	}, opts...)
+	fh, err := st.NewFramework(registeredPlugins, "default-scheduler", wait.NeverStop, opts...)
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+	return fh
+}
+
+// startInformers starts the shared informers of the handle the way the
+// scheduler does once every plugin is built, and waits for them to sync. The
+// returned func stops them again.
+func startInformers(t testing.TB, fh framework.Handle) func() {
+	t.Helper()
+	stopCh := make(chan struct{})
+	fh.SharedInformerFactory().Start(stopCh)
+	fh.SharedInformerFactory().WaitForCacheSync(stopCh)
+	return func() {
+		close(stopCh)
+		fh.SharedInformerFactory().Shutdown()
+	}
+}
+
+// scoreNodes runs Score on every node followed by NormalizeScore, the way the
+// framework does, and returns the normalized scores.
+func scoreNodes(t *testing.T, cs *CustomScheduler, state *framework.CycleState, pod *v1.Pod, nodes []*framework.NodeInfo) framework.NodeScoreList {
+	t.Helper()
+	scores := make(framework.NodeScoreList, 0, len(nodes))
+	for _, ni := range nodes {
+		score, status := cs.Score(context.Background(), state, pod, ni.Node().Name)
+		if !status.IsSuccess() {
+			t.Fatalf("unexpected Score error on node %s: %v", ni.Node().Name, status)
+		}
+		scores = append(scores, framework.NodeScore{Name: ni.Node().Name, Score: score})
+	}
+	if extensions := cs.ScoreExtensions(); extensions != nil {
+		if status := extensions.NormalizeScore(context.Background(), state, pod, scores); !status.IsSuccess() {
+			t.Fatalf("unexpected NormalizeScore error: %v", status)
+		}
+	}
+	return scores
+}
+
+// warmingUpHandle mimics a handle during scheduler warm-up, before the
+// snapshot and the informers are wired up.
+type warmingUpHandle struct {
+	framework.Handle
+}
+
+func (warmingUpHandle) SnapshotSharedLister() framework.SharedLister {
+	return nil
+}
+
+func (warmingUpHandle) SharedInformerFactory() informers.SharedInformerFactory {
+	return nil
+}
+
+func TestNewTestHandle(t *testing.T) {
+	nodes := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 300),
+		makeNodeInfo("m3", 1000, 200),
+	}
+	pods := []*v1.Pod{}
+	for _, name := range []string{"pod0", "pod1"} {
+		pods = append(pods, st.MakePod().Name(name).Namespace("default").
+			Label("podGroup", "g1").Label("minAvailable", "2").Obj())
+	}
+	fh := newTestHandle(t, nodes, pods)
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode}
+
+	if _, status := cs.PreFilter(context.Background(), nil, pods[0]); !status.IsSuccess() {
+		t.Fatalf("expected PreFilter to pass, got %v", status)
+	}
+
+	want := map[string]int64{"m1": framework.MinNodeScore, "m2": framework.MaxNodeScore, "m3": 50}
+	for _, s := range scoreNodes(t, cs, nil, pods[0], nodes) {
+		if s.Score != want[s.Name] {
+			t.Errorf("expected node %s to score %d, got %d", s.Name, want[s.Name], s.Score)
+		}
+	}
+}
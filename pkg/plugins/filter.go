@@ -0,0 +1,70 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ framework.FilterPlugin = &CustomScheduler{}
+
+// Filter drops nodes that are cordoned or NotReady in the snapshot, so no
+// scoring slot is spent on a node that would reject the bind. Preemption
+// can't fix either state, so both are unresolvable. With minFreeMemory set it
+// also drops nodes whose unrequested memory is below it, with
+// groupTopologyKey set the nodes that would skew the spread of the pod's
+// group, and with filterPressuredNodes set the nodes under memory or disk
+// pressure.
+func (cs *CustomScheduler) Filter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	node := nodeInfo.Node()
+	if node == nil {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, "node not found")
+	}
+	if status := readinessRejection(node); status != nil {
+		return status
+	}
+	if status := cs.pressureRejection(node); status != nil {
+		return status
+	}
+	if status := cs.spreadRejection(state, node); status != nil {
+		return status
+	}
+	if cs.minFreeMemory > 0 {
+		var free int64
+		if nodeInfo.Allocatable != nil && nodeInfo.Requested != nil {
+			free = memoryScore(nodeInfo) - nodeInfo.Requested.Memory
+		}
+		if free < cs.minFreeMemory {
+			return nodeRejection(framework.Unschedulable, node.Name, "free-memory", "has %s free, minimum is %s",
+				resource.NewQuantity(free, resource.BinarySI), resource.NewQuantity(cs.minFreeMemory, resource.BinarySI))
+		}
+	}
+	return nil
+}
+
+// readinessRejection rejects a cordoned or NotReady node, it returns nil for
+// any other node.
+func readinessRejection(node *v1.Node) *framework.Status {
+	if node.Spec.Unschedulable {
+		return nodeRejection(framework.UnschedulableAndUnresolvable, node.Name, "readiness", "node is cordoned")
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == v1.NodeReady && cond.Status == v1.ConditionFalse {
+			return nodeRejection(framework.UnschedulableAndUnresolvable, node.Name, "readiness", "node is NotReady: %s", cond.Reason)
+		}
+	}
+	return nil
+}
+
+// nodeRejection builds the status returned when a node is filtered out. Every
+// rejection names the node and the failing predicate and carries the node's
+// relevant value, so the per-node reasons shown by `kubectl describe pod` are
+// specific enough to act on, e.g.
+//
+//	node m1 rejected by free-memory: has 1Gi free, minimum is 2Gi
+func nodeRejection(code framework.Code, nodeName, predicate, format string, args ...interface{}) *framework.Status {
+	return framework.NewStatus(code, fmt.Sprintf("node %s rejected by %s: %s", nodeName, predicate, fmt.Sprintf(format, args...)))
+}
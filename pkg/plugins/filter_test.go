@@ -0,0 +1,262 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+func TestNodeRejection(t *testing.T) {
+	status := nodeRejection(framework.UnschedulableAndUnresolvable, "m1", "gpu", "has %d nvidia.com/gpu, pod requires GPU mode", 0)
+	if status.Code() != framework.UnschedulableAndUnresolvable {
+		t.Errorf("expected %v, got %v", framework.UnschedulableAndUnresolvable, status.Code())
+	}
+	want := "node m1 rejected by gpu: has 0 nvidia.com/gpu, pod requires GPU mode"
+	if status.Message() != want {
+		t.Errorf("expected %q, got %q", want, status.Message())
+	}
+}
+
+func TestCustomScheduler_Filter(t *testing.T) {
+	cordoned := makeNodeInfo("cordoned", 1000, 100)
+	cordoned.Node().Spec.Unschedulable = true
+	notReady := makeNodeInfo("not-ready", 1000, 100)
+	notReady.Node().Status.Conditions = []v1.NodeCondition{
+		{Type: v1.NodeReady, Status: v1.ConditionFalse, Reason: "KubeletNotReady"},
+	}
+	ready := makeNodeInfo("ready", 1000, 100)
+	ready.Node().Status.Conditions = []v1.NodeCondition{
+		{Type: v1.NodeReady, Status: v1.ConditionTrue},
+	}
+
+	tests := []struct {
+		name     string
+		nodeInfo *framework.NodeInfo
+		want     *framework.Status
+	}{
+		{
+			name:     "cordoned node",
+			nodeInfo: cordoned,
+			want:     framework.NewStatus(framework.UnschedulableAndUnresolvable, "node cordoned rejected by readiness: node is cordoned"),
+		},
+		{
+			name:     "not ready node",
+			nodeInfo: notReady,
+			want:     framework.NewStatus(framework.UnschedulableAndUnresolvable, "node not-ready rejected by readiness: node is NotReady: KubeletNotReady"),
+		},
+		{name: "ready node", nodeInfo: ready, want: nil},
+		{name: "node without conditions", nodeInfo: makeNodeInfo("m1", 1000, 100), want: nil},
+	}
+	cs := &CustomScheduler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cs.Filter(context.Background(), nil, &v1.Pod{}, tt.nodeInfo)
+			if got.Code() != tt.want.Code() || got.Message() != tt.want.Message() {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_FilterMinFreeMemory(t *testing.T) {
+	const gi = int64(1 << 30)
+	cs := &CustomScheduler{minFreeMemory: 2 * gi}
+
+	tests := []struct {
+		name     string
+		nodeInfo *framework.NodeInfo
+		want     *framework.Status
+	}{
+		{name: "above the floor", nodeInfo: makeNodeInfo("m1", 1000, 3*gi), want: nil},
+		{name: "at the floor", nodeInfo: makeNodeInfo("m2", 1000, 2*gi), want: nil},
+		{
+			name:     "one byte below the floor",
+			nodeInfo: makeNodeInfo("m3", 1000, 2*gi-1),
+			want:     framework.NewStatus(framework.Unschedulable, "node m3 rejected by free-memory: has 2147483647 free, minimum is 2Gi"),
+		},
+		{
+			name:     "requests eat into the floor",
+			nodeInfo: makeNodeInfoWithPods("m4", 1000, 3*gi, makePodWithMemory("p0", 2*gi)),
+			want:     framework.NewStatus(framework.Unschedulable, "node m4 rejected by free-memory: has 1Gi free, minimum is 2Gi"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cs.Filter(context.Background(), nil, &v1.Pod{}, tt.nodeInfo)
+			if got.Code() != tt.want.Code() || got.Message() != tt.want.Message() {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// runPreFilterFilter runs the plugin's PreFilter and then its Filter on the
+// node through the framework, which skips Filter when PreFilter says so.
+func runPreFilterFilter(t *testing.T, cs *CustomScheduler, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	t.Helper()
+	factory := func(_ runtime.Object, h framework.Handle) (framework.Plugin, error) {
+		cs.handle = h
+		return cs, nil
+	}
+	fwk := newTestFramework(t, []st.RegisterPluginFunc{st.RegisterPluginAsExtensions(Name, factory, "PreFilter", "Filter")},
+		[]*framework.NodeInfo{nodeInfo}, nil)
+	ctx := context.Background()
+	state := framework.NewCycleState()
+	if _, status := fwk.RunPreFilterPlugins(ctx, state, pod); !status.IsSuccess() {
+		t.Fatalf("unexpected PreFilter status: %v", status)
+	}
+	return fwk.RunFilterPlugins(ctx, state, pod, nodeInfo)
+}
+
+func TestCustomScheduler_FilterAfterPreFilter(t *testing.T) {
+	cordoned := makeNodeInfo("cordoned", 1000, 100)
+	cordoned.Node().Spec.Unschedulable = true
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+	}{
+		{name: "ungrouped pod", pod: st.MakePod().Name("pod0").Namespace("default").Obj()},
+		{name: "pod of an excluded namespace", pod: st.MakePod().Name("pod0").Namespace("kube-system").Label("podGroup", "g1").Label("minAvailable", "3").Obj()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{scoreMode: leastMode, skipUngrouped: true, excludedNamespaces: namespaceSet(defaultExcludedNamespaces)}
+			if status := runPreFilterFilter(t, cs, tt.pod, cordoned); status.Code() != framework.UnschedulableAndUnresolvable {
+				t.Errorf("expected the cordoned node rejected, got %v", status)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_FilterMinFreeMemoryAfterPreFilter(t *testing.T) {
+	small := makeNodeInfo("small", 1000, 1<<20)
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+	}{
+		{name: "ungrouped pod", pod: st.MakePod().Name("pod0").Namespace("default").Obj()},
+		{name: "pod of an excluded namespace", pod: st.MakePod().Name("pod0").Namespace("kube-system").Label("podGroup", "g1").Label("minAvailable", "3").Obj()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{scoreMode: leastMode, skipUngrouped: true, excludedNamespaces: namespaceSet(defaultExcludedNamespaces), minFreeMemory: 2 << 30}
+			if status := runPreFilterFilter(t, cs, tt.pod, small); status.Code() != framework.Unschedulable {
+				t.Errorf("expected the node below minFreeMemory rejected, got %v", status)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_FilterPressure(t *testing.T) {
+	pressured := makeNodeInfo("pressured", 1000, 100)
+	pressured.Node().Status.Conditions = []v1.NodeCondition{
+		{Type: v1.NodeReady, Status: v1.ConditionTrue},
+		{Type: v1.NodeMemoryPressure, Status: v1.ConditionTrue},
+	}
+	relieved := makeNodeInfo("relieved", 1000, 100)
+	relieved.Node().Status.Conditions = []v1.NodeCondition{
+		{Type: v1.NodeDiskPressure, Status: v1.ConditionFalse},
+	}
+
+	tests := []struct {
+		name     string
+		filter   bool
+		nodeInfo *framework.NodeInfo
+		want     *framework.Status
+	}{
+		{
+			name:     "pressured node filtered",
+			filter:   true,
+			nodeInfo: pressured,
+			want:     framework.NewStatus(framework.UnschedulableAndUnresolvable, "node pressured rejected by pressure: node has MemoryPressure"),
+		},
+		{name: "pressure cleared", filter: true, nodeInfo: relieved, want: nil},
+		{name: "pressured node only penalized", filter: false, nodeInfo: pressured, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{filterPressuredNodes: tt.filter}
+			got := cs.Filter(context.Background(), nil, &v1.Pod{}, tt.nodeInfo)
+			if got.Code() != tt.want.Code() || got.Message() != tt.want.Message() {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_FilterPressureAfterPreFilter(t *testing.T) {
+	pressured := makeNodeInfo("pressured", 1000, 100)
+	pressured.Node().Status.Conditions = []v1.NodeCondition{{Type: v1.NodeDiskPressure, Status: v1.ConditionTrue}}
+	pods := []*v1.Pod{
+		st.MakePod().Name("pod0").Namespace("default").Obj(),
+		st.MakePod().Name("pod1").Namespace("kube-system").Label("podGroup", "g1").Label("minAvailable", "3").Obj(),
+	}
+	for _, pod := range pods {
+		cs := &CustomScheduler{scoreMode: leastMode, skipUngrouped: true, excludedNamespaces: namespaceSet(defaultExcludedNamespaces), filterPressuredNodes: true}
+		if status := runPreFilterFilter(t, cs, pod, pressured); status.Code() != framework.UnschedulableAndUnresolvable {
+			t.Errorf("expected the pressured node rejected for pod %s/%s, got %v", pod.Namespace, pod.Name, status)
+		}
+	}
+}
+
+func TestCustomScheduler_FilterGroupSpread(t *testing.T) {
+	member := func(name string) *v1.Pod {
+		return st.MakePod().Name(name).UID(name).Namespace("default").Label("podGroup", "g1").Label("minAvailable", "1").Obj()
+	}
+	inZone := func(name, zone string, pods ...*v1.Pod) *framework.NodeInfo {
+		ni := makeNodeInfoWithPods(name, 1000, 100, pods...)
+		if zone != "" {
+			ni.Node().Labels = map[string]string{v1.LabelTopologyZone: zone}
+		}
+		return ni
+	}
+	pod := member("pod")
+
+	tests := []struct {
+		name     string
+		nodes    []*framework.NodeInfo
+		rejected map[string]string
+	}{
+		{
+			name:  "balanced",
+			nodes: []*framework.NodeInfo{inZone("a1", "a", member("m0")), inZone("b1", "b", member("m1")), inZone("c1", "c", member("m2"))},
+		},
+		{
+			name: "skewed",
+			nodes: []*framework.NodeInfo{
+				inZone("a1", "a", member("m0")),
+				inZone("b1", "b", member("m1")),
+				inZone("c1", "c"),
+				// other groups don't count
+				inZone("c2", "c", st.MakePod().Name("other").UID("other").Label("podGroup", "g2").Obj()),
+				inZone("none", ""),
+			},
+			rejected: map[string]string{
+				"a1":   "node a1 rejected by group-spread: topology.kubernetes.io/zone a would have 2 group members, skew 2 exceeds 1",
+				"b1":   "node b1 rejected by group-spread: topology.kubernetes.io/zone b would have 2 group members, skew 2 exceeds 1",
+				"none": "node none rejected by group-spread: node has no topology.kubernetes.io/zone label",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, tt.nodes, []*v1.Pod{pod})
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode, groupTopologyKey: v1.LabelTopologyZone}
+			state := framework.NewCycleState()
+			if _, status := cs.PreFilter(context.Background(), state, pod); !status.IsSuccess() {
+				t.Fatalf("expected PreFilter to pass, got %v", status)
+			}
+			for _, ni := range tt.nodes {
+				got := cs.Filter(context.Background(), state, pod, ni)
+				want, rejected := tt.rejected[ni.Node().Name]
+				if got.IsSuccess() == rejected || got.Message() != want {
+					t.Errorf("expected node %s rejected %v with %q, got %v", ni.Node().Name, rejected, want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// gangSchedulingGate is the scheduling gate that holds group members outside
+// the scheduling queue until the group reaches minAvailable.
+const gangSchedulingGate string = "scheduling.nthu/gang-hold"
+
+// legacyGangSchedulingGate is the gang gate's former name, still released
+// so pods created before the rename aren't held forever.
+const legacyGangSchedulingGate string = "customscheduler.example.com/gang"
+
+// startGangGates releases the gang gate of every member of a group once
+// the group reaches minAvailable, so the members enter scheduling together
+// instead of waiting in the queue. Pods are handled by a single worker fed by
+// the pods informer, it stops when the plugin is closed.
+func (cs *CustomScheduler) startGangGates(informer cache.SharedIndexInformer) error {
+	queue := workqueue.New()
+	enqueue := func(obj interface{}) {
+		pod, ok := obj.(*v1.Pod)
+		if !ok {
+			return
+		}
+		if _, ok := cs.groupName(pod); !ok {
+			return
+		}
+		if key, err := cache.MetaNamespaceKeyFunc(pod); err == nil {
+			queue.Add(key)
+		}
+	}
+	reg, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, newObj interface{}) { enqueue(newObj) },
+	})
+	if err != nil {
+		return fmt.Errorf("error registering gang gate handler: %v", err)
+	}
+	cs.handlers = append(cs.handlers, handlerRegistration{informer: informer, registration: reg})
+
+	go func() {
+		<-cs.stopCh
+		queue.ShutDown()
+	}()
+	go func() {
+		for {
+			item, shutdown := queue.Get()
+			if shutdown {
+				return
+			}
+			// failed releases are retried on the next event of the group
+			if err := cs.releaseGangGates(item.(string)); err != nil {
+				log.Printf("Warning: error releasing gang gates for pod %s: %v", item, err)
+			}
+			queue.Done(item)
+		}
+	}()
+	return nil
+}
+
+// releaseGangGates removes the gang gate from the members of the pod's
+// group once enough of them exist. Members held only by the gang gate count
+// toward minAvailable here, they are the ones waiting on it.
+//
+// Gating keeps unready members out of the scheduling queue entirely, unlike
+// PreFilter rejections or Permit waits, at the cost of an API write per member
+// and of the members only being released once this controller sees the group
+// complete.
+func (cs *CustomScheduler) releaseGangGates(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	pod, err := cs.handle.SharedInformerFactory().Core().V1().Pods().Lister().Pods(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, pods, ok, err := cs.groupMembers(pod)
+	if err != nil || !ok {
+		return err
+	}
+	// the same minimum PreFilter gates the members with
+	minAvailable, byRole, err := cs.gangMinimum(pod)
+	if err != nil {
+		return err
+	}
+
+	var gated []*v1.Pod
+	candidates := make([]*v1.Pod, 0, len(pods))
+	for _, p := range pods {
+		if hasGangGate(p) {
+			gated = append(gated, p)
+			p = p.DeepCopy()
+			p.Spec.SchedulingGates = withoutGangGates(p.Spec.SchedulingGates)
+		}
+		candidates = append(candidates, p)
+	}
+	if len(gated) == 0 || cs.countMembers(candidates) < minAvailable {
+		return nil
+	}
+	if _, _, _, short := cs.shortRole(candidates, byRole); short {
+		return nil
+	}
+
+	for _, p := range gated {
+		if err := cs.removeGangGate(p); err != nil {
+			return err
+		}
+	}
+	log.Printf("Released the gang gate of %d pods in the group of pod %s.", len(gated), pod.Name)
+	return nil
+}
+
+// removeGangGate strips the gang gates off the pod. The informer's copy may
+// be stale, or the pod updated concurrently, so on a conflict the pod is read
+// again from the API before retrying.
+func (cs *CustomScheduler) removeGangGate(pod *v1.Pod) error {
+	pods := cs.handle.ClientSet().CoreV1().Pods(pod.Namespace)
+	current := pod
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if current == nil {
+			latest, err := pods.Get(context.Background(), pod.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			current = latest
+		}
+		// released by someone else, or recreated without the gate
+		if !hasGangGate(current) {
+			return nil
+		}
+		updated := current.DeepCopy()
+		updated.Spec.SchedulingGates = withoutGangGates(updated.Spec.SchedulingGates)
+		_, err := pods.Update(context.Background(), updated, metav1.UpdateOptions{})
+		current = nil
+		return err
+	})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error removing the gang gate of pod %s: %v", pod.Name, err)
+	}
+	return nil
+}
+
+// withoutGangGates returns the gates other than the gang gates.
+func withoutGangGates(gates []v1.PodSchedulingGate) []v1.PodSchedulingGate {
+	var kept []v1.PodSchedulingGate
+	for _, g := range gates {
+		if !isGangGate(g.Name) {
+			kept = append(kept, g)
+		}
+	}
+	return kept
+}
+
+// isGangGate reports whether the named scheduling gate is a gang gate.
+func isGangGate(name string) bool {
+	return name == gangSchedulingGate || name == legacyGangSchedulingGate
+}
+
+// hasGangGate reports whether the pod is held by the gang gate.
+func hasGangGate(pod *v1.Pod) bool {
+	for _, g := range pod.Spec.SchedulingGates {
+		if isGangGate(g.Name) {
+			return true
+		}
+	}
+	return false
+}
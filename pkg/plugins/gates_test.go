@@ -0,0 +1,247 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+func makeGangGatedPods(n, minAvailable int) []*v1.Pod {
+	pods := []*v1.Pod{}
+	for i := 0; i < n; i++ {
+		pods = append(pods, st.MakePod().Name(fmt.Sprintf("pod%d", i)).Namespace("default").
+			UID(fmt.Sprintf("uid%d", i)).Label("podGroup", "g1").Label("minAvailable", fmt.Sprint(minAvailable)).
+			SchedulingGates([]string{gangSchedulingGate}).Phase(v1.PodPending).Obj())
+	}
+	return pods
+}
+
+func TestCustomScheduler_ReleaseGangGates(t *testing.T) {
+	tests := []struct {
+		name      string
+		pods      []*v1.Pod
+		wantGated bool
+	}{
+		{name: "group reaches minAvailable", pods: makeGangGatedPods(3, 3), wantGated: false},
+		{name: "group short of minAvailable", pods: makeGangGatedPods(2, 3), wantGated: true},
+		{
+			name: "legacy gate",
+			pods: func() []*v1.Pod {
+				pods := makeGangGatedPods(3, 3)
+				pods[1].Spec.SchedulingGates = []v1.PodSchedulingGate{{Name: legacyGangSchedulingGate}}
+				return pods
+			}(),
+			wantGated: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, nil, tt.pods)
+			cs := &CustomScheduler{handle: fh}
+			if err := cs.releaseGangGates("default/pod0"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, p := range tt.pods {
+				got, err := fh.ClientSet().CoreV1().Pods("default").Get(context.Background(), p.Name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("fail to get pod: %s", err)
+				}
+				if hasGangGate(got) != tt.wantGated {
+					t.Errorf("expected pod %s gated %v, got %v", p.Name, tt.wantGated, hasGangGate(got))
+				}
+			}
+		})
+	}
+}
+
+func makeGangGatedRolePods(roles ...string) []*v1.Pod {
+	pods := []*v1.Pod{}
+	for i, role := range roles {
+		pods = append(pods, st.MakePod().Name(fmt.Sprintf("pod%d", i)).Namespace("default").
+			UID(fmt.Sprintf("uid%d", i)).Label("podGroup", "g1").Label("podRole", role).
+			Annotation("minAvailableByRole", `{"driver": 1, "worker": 3}`).
+			SchedulingGates([]string{gangSchedulingGate}).Phase(v1.PodPending).Obj())
+	}
+	return pods
+}
+
+func TestCustomScheduler_ReleaseGangGatesRoleMinimum(t *testing.T) {
+	// the role minimums add up to 4, above the default minimum of 1
+	pods := makeGangGatedRolePods("driver", "worker")
+	fh := newTestHandle(t, nil, pods)
+	cs := &CustomScheduler{handle: fh, defaultMinAvailable: 1}
+	if err := cs.releaseGangGates("default/pod0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range pods {
+		got, err := fh.ClientSet().CoreV1().Pods("default").Get(context.Background(), p.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("fail to get pod: %s", err)
+		}
+		if !hasGangGate(got) {
+			t.Errorf("expected pod %s held with 2 of 4 members present", p.Name)
+		}
+	}
+}
+
+func TestCustomScheduler_ReleaseGangGatesRoleShort(t *testing.T) {
+	pods := makeGangGatedRolePods("worker", "worker", "worker", "worker", "driver")
+	fh := newTestHandle(t, nil, pods[:4])
+	cs := &CustomScheduler{handle: fh}
+	gated := func(name string) bool {
+		got, err := fh.ClientSet().CoreV1().Pods("default").Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("fail to get pod: %s", err)
+		}
+		return hasGangGate(got)
+	}
+
+	// 4 members meet the total, but no driver is present
+	if err := cs.releaseGangGates("default/pod0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range pods[:4] {
+		if !gated(p.Name) {
+			t.Errorf("expected pod %s held while the driver role is short", p.Name)
+		}
+	}
+
+	if _, err := fh.ClientSet().CoreV1().Pods("default").Create(context.Background(), pods[4], metav1.CreateOptions{}); err != nil {
+		t.Fatalf("fail to create pod: %s", err)
+	}
+	if err := fh.SharedInformerFactory().Core().V1().Pods().Informer().GetStore().Add(pods[4]); err != nil {
+		t.Fatalf("fail to add pod: %s", err)
+	}
+	if err := cs.releaseGangGates("default/pod4"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range pods {
+		if gated(p.Name) {
+			t.Errorf("expected pod %s released once every role is met", p.Name)
+		}
+	}
+}
+
+func TestCustomScheduler_ReleaseGangGatesWhenReady(t *testing.T) {
+	pods := makeGangGatedPods(3, 3)
+	fh := newTestHandle(t, nil, pods[:2])
+	cs := &CustomScheduler{handle: fh}
+	gated := func(name string) bool {
+		got, err := fh.ClientSet().CoreV1().Pods("default").Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("fail to get pod: %s", err)
+		}
+		return hasGangGate(got)
+	}
+
+	if err := cs.releaseGangGates("default/pod0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !gated("pod0") || !gated("pod1") {
+		t.Fatalf("expected the group held while short of minAvailable")
+	}
+
+	// the last member shows up
+	if _, err := fh.ClientSet().CoreV1().Pods("default").Create(context.Background(), pods[2], metav1.CreateOptions{}); err != nil {
+		t.Fatalf("fail to create pod: %s", err)
+	}
+	if err := fh.SharedInformerFactory().Core().V1().Pods().Informer().GetStore().Add(pods[2]); err != nil {
+		t.Fatalf("fail to add pod: %s", err)
+	}
+	if err := cs.releaseGangGates("default/pod2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range pods {
+		if gated(p.Name) {
+			t.Errorf("expected pod %s released once the group is ready", p.Name)
+		}
+	}
+}
+
+func TestCustomScheduler_ReleaseGangGatesConflict(t *testing.T) {
+	pods := makeGangGatedPods(2, 2)
+	fh := newTestHandle(t, nil, pods)
+	// the first update races with another writer of pod0
+	client := fh.ClientSet().(*clientsetfake.Clientset)
+	conflicts := 0
+	client.PrependReactor("update", "pods", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			return false, nil, nil
+		}
+		conflicts++
+		return true, nil, apierrors.NewConflict(v1.Resource("pods"), "pod0", fmt.Errorf("the object has been modified"))
+	})
+	cs := &CustomScheduler{handle: fh}
+	if err := cs.releaseGangGates("default/pod0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	refetched := false
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "get" {
+			refetched = true
+		}
+	}
+	if !refetched {
+		t.Errorf("expected the conflicting pod read again before retrying")
+	}
+	for _, p := range pods {
+		got, err := client.CoreV1().Pods("default").Get(context.Background(), p.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("fail to get pod: %s", err)
+		}
+		if hasGangGate(got) {
+			t.Errorf("expected pod %s released despite the conflict", p.Name)
+		}
+	}
+}
+
+func TestNew_ManageGangGates(t *testing.T) {
+	pods := makeGangGatedPods(4, 3)
+	// pod3 is also held by another gate, so it doesn't count, but its gang
+	// gate is released with the rest and the other gate stays in place
+	pods[3].Spec.SchedulingGates = append(pods[3].Spec.SchedulingGates, v1.PodSchedulingGate{Name: "example.com/quota"})
+	fh := newTestHandle(t, nil, pods)
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	p, err := New(&runtime.Unknown{Raw: []byte(`{"mode": "Least", "manageGangGates": true}`)}, fh)
+	if err != nil {
+		t.Fatalf("fail to create plugin: %s", err)
+	}
+	stopInformers := startInformers(t, fh)
+	defer func() {
+		p.(*CustomScheduler).Close()
+		stopInformers()
+	}()
+
+	err = wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		for _, want := range pods {
+			got, err := fh.ClientSet().CoreV1().Pods("default").Get(context.Background(), want.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			if hasGangGate(got) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("expected every gang gate released: %v", err)
+	}
+	got, _ := fh.ClientSet().CoreV1().Pods("default").Get(context.Background(), "pod3", metav1.GetOptions{})
+	if len(got.Spec.SchedulingGates) != 1 || got.Spec.SchedulingGates[0].Name != "example.com/quota" {
+		t.Errorf("expected only the other gate left on pod3, got %v", got.Spec.SchedulingGates)
+	}
+}
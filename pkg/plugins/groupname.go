@@ -0,0 +1,33 @@
+package plugins
+
+// GroupNameNormalizer rewrites group names matching Pattern with
+// Replacement, which may refer to the submatches as in
+// regexp.Regexp.ReplaceAllString, e.g. "^(train-job)-[0-9]+$" and "$1-*".
+type GroupNameNormalizer struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// unnormalizedGroup is the group label of metrics for groups no
+// GroupNameNormalizer rewrites.
+const unnormalizedGroup string = "other"
+
+// reportedGroupName returns the name the group is reported as in metrics
+// and events, collapsing dynamic parts so the label set stays small. The
+// group itself is still matched by its real name.
+func (cs *CustomScheduler) reportedGroupName(group string) string {
+	if cs.groupNamePattern == nil {
+		return group
+	}
+	return cs.groupNamePattern.ReplaceAllString(group, cs.groupNameReplacement)
+}
+
+// metricGroupName returns the group label of the group's metrics, the
+// reported name when the normalizer's pattern matches the group and
+// unnormalizedGroup otherwise, so the label set stays bounded.
+func (cs *CustomScheduler) metricGroupName(group string) string {
+	if cs.groupNamePattern == nil || !cs.groupNamePattern.MatchString(group) {
+		return unnormalizedGroup
+	}
+	return cs.reportedGroupName(group)
+}
@@ -0,0 +1,116 @@
+package plugins
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/component-base/metrics/testutil"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+func TestCustomScheduler_ReportedGroupName(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		group   string
+		want    string
+	}{
+		{name: "no normalizer", group: "train-job-123", want: "train-job-123"},
+		{name: "dynamic suffix", pattern: "^(train-job)-[0-9]+$", group: "train-job-123", want: "train-job-*"},
+		{name: "no match", pattern: "^(train-job)-[0-9]+$", group: "serve-api", want: "serve-api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{}
+			if tt.pattern != "" {
+				cs.groupNamePattern = regexp.MustCompile(tt.pattern)
+				cs.groupNameReplacement = "$1-*"
+			}
+			if got := cs.reportedGroupName(tt.group); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_MetricGroupName(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		group   string
+		want    string
+	}{
+		{name: "no normalizer", group: "train-job-123", want: unnormalizedGroup},
+		{name: "dynamic suffix", pattern: "^(train-job)-[0-9]+$", group: "train-job-123", want: "train-job-*"},
+		{name: "no match", pattern: "^(train-job)-[0-9]+$", group: "serve-api", want: unnormalizedGroup},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{}
+			if tt.pattern != "" {
+				cs.groupNamePattern = regexp.MustCompile(tt.pattern)
+				cs.groupNameReplacement = "$1-*"
+			}
+			if got := cs.metricGroupName(tt.group); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterGroupNameNormalizer(t *testing.T) {
+	pod := st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "train-job-123").Label("minAvailable", "2").Phase(v1.PodPending).Obj()
+	fh := newTestHandle(t, nil, []*v1.Pod{pod})
+	p, err := New(&runtime.Unknown{Raw: []byte(`{"mode": "Least", "groupNameNormalizer": {"pattern": "^(train-job)-[0-9]+$", "replacement": "$1-*"}}`)}, fh)
+	if err != nil {
+		t.Fatalf("fail to create plugin: %s", err)
+	}
+	cs := p.(*CustomScheduler)
+	defer func() {
+		cs.Close()
+		fh.SharedInformerFactory().Shutdown()
+	}()
+
+	normalized := gangChecksTotal.WithLabelValues("train-job-*", gangRejected)
+	raw := gangChecksTotal.WithLabelValues("train-job-123", gangRejected)
+	before, err := testutil.GetCounterMetricValue(normalized)
+	if err != nil {
+		t.Fatalf("fail to read metric: %s", err)
+	}
+	cs.PreFilter(context.Background(), nil, pod)
+	after, err := testutil.GetCounterMetricValue(normalized)
+	if err != nil {
+		t.Fatalf("fail to read metric: %s", err)
+	}
+	if after-before != 1 {
+		t.Errorf("expected the check counted under train-job-*, got %v", after-before)
+	}
+	if got, _ := testutil.GetCounterMetricValue(raw); got != 0 {
+		t.Errorf("expected no check counted under the raw group name, got %v", got)
+	}
+}
+
+func TestCustomScheduler_GangChecksWithoutNormalizer(t *testing.T) {
+	registerMetrics()
+	cs := &CustomScheduler{}
+	bucket := gangChecksTotal.WithLabelValues(unnormalizedGroup, gangPassed)
+	raw := gangChecksTotal.WithLabelValues("train-job-456", gangPassed)
+	before, err := testutil.GetCounterMetricValue(bucket)
+	if err != nil {
+		t.Fatalf("fail to read metric: %s", err)
+	}
+	cs.observeGangSize("train-job-456", 2, 2)
+	after, err := testutil.GetCounterMetricValue(bucket)
+	if err != nil {
+		t.Fatalf("fail to read metric: %s", err)
+	}
+	if after-before != 1 {
+		t.Errorf("expected the check counted under %s, got %v", unnormalizedGroup, after-before)
+	}
+	if got, _ := testutil.GetCounterMetricValue(raw); got != 0 {
+		t.Errorf("expected no check counted under the raw group name, got %v", got)
+	}
+}
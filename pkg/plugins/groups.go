@@ -0,0 +1,186 @@
+package plugins
+
+import (
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// groupCounter keeps the members of every pod group observed by the pods
+// informer. It is fed by informer event handlers, so after a scheduler
+// restart the initial list of the informer replays every existing pod and the
+// counts are rebuilt without any extra bookkeeping.
+type groupCounter struct {
+	mu      sync.RWMutex
+	members map[string]map[types.UID]struct{}
+	// ready holds the groups PreFilter last saw meeting minAvailable.
+	ready map[string]bool
+	// bound holds the members already assigned to a node.
+	bound map[string]map[types.UID]struct{}
+	// active holds when the groups that passed PreFilter and aren't fully
+	// bound took their slot.
+	active map[string]time.Time
+	// groupOf names the group of a pod, ok is false for ungrouped pods.
+	groupOf func(pod *v1.Pod) (group string, ok bool)
+}
+
+func newGroupCounter(groupOf func(pod *v1.Pod) (string, bool)) *groupCounter {
+	return &groupCounter{
+		members: map[string]map[types.UID]struct{}{},
+		ready:   map[string]bool{},
+		bound:   map[string]map[types.UID]struct{}{},
+		active:  map[string]time.Time{},
+		groupOf: groupOf,
+	}
+}
+
+// eventHandler returns the handler to register on the pods informer.
+func (c *groupCounter) eventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*v1.Pod); ok {
+				c.add(pod)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, ok := oldObj.(*v1.Pod)
+			if !ok {
+				return
+			}
+			if newPod, ok := newObj.(*v1.Pod); ok {
+				c.update(oldPod, newPod)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pod, ok := obj.(*v1.Pod); ok {
+				c.remove(pod)
+			}
+		},
+	}
+}
+
+// add records the pod as a member of its group. Adding the same pod more than
+// once is a no-op, which keeps replays from the informer idempotent.
+func (c *groupCounter) add(pod *v1.Pod) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addLocked(pod)
+}
+
+// remove drops the pod from its group.
+func (c *groupCounter) remove(pod *v1.Pod) {
+	group, ok := c.groupOf(pod)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dropLocked(group, pod.UID)
+	c.cleanupLocked(group)
+}
+
+// update moves the pod from its old state to the new one. The old group is
+// only cleaned up after the new state is recorded, so an update doesn't
+// reset the ready and active state of a group it leaves non-empty.
+func (c *groupCounter) update(oldPod, newPod *v1.Pod) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	oldGroup, ok := c.groupOf(oldPod)
+	if ok {
+		c.dropLocked(oldGroup, oldPod.UID)
+	}
+	c.addLocked(newPod)
+	if ok {
+		c.cleanupLocked(oldGroup)
+	}
+}
+
+func (c *groupCounter) addLocked(pod *v1.Pod) {
+	group, ok := c.groupOf(pod)
+	if !ok || len(pod.Spec.SchedulingGates) > 0 {
+		return
+	}
+	if c.members[group] == nil {
+		c.members[group] = map[types.UID]struct{}{}
+	}
+	c.members[group][pod.UID] = struct{}{}
+	if pod.Spec.NodeName != "" {
+		if c.bound[group] == nil {
+			c.bound[group] = map[types.UID]struct{}{}
+		}
+		c.bound[group][pod.UID] = struct{}{}
+	}
+	// the gang is placed, free its slot for the next one
+	if len(c.bound[group]) == len(c.members[group]) {
+		delete(c.active, group)
+	}
+}
+
+func (c *groupCounter) dropLocked(group string, uid types.UID) {
+	delete(c.members[group], uid)
+	delete(c.bound[group], uid)
+}
+
+// cleanupLocked forgets every state of the group once it has no members.
+func (c *groupCounter) cleanupLocked(group string) {
+	if len(c.members[group]) == 0 {
+		delete(c.members, group)
+		delete(c.bound, group)
+		delete(c.ready, group)
+		delete(c.active, group)
+	}
+}
+
+// count returns the number of known members of the group.
+func (c *groupCounter) count(group string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.members[group])
+}
+
+// setReady records whether the group meets minAvailable and reports whether
+// it just became ready. A group never seen before starts as not ready.
+func (c *groupCounter) setReady(group string, ready bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	was := c.ready[group]
+	if ready {
+		c.ready[group] = true
+	} else {
+		delete(c.ready, group)
+		// a group that fell short no longer holds an active slot
+		delete(c.active, group)
+	}
+	return ready && !was
+}
+
+// activate marks the group as being scheduled. It reports false when max
+// other groups are already active, a max of zero means no limit. Slots taken
+// more than ttl before now are freed first, so a gang that never gets placed
+// doesn't hold its slot forever, a ttl of zero keeps them until the gang is
+// bound.
+func (c *groupCounter) activate(group string, max int, now time.Time, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ttl > 0 {
+		for g, since := range c.active {
+			if !now.Before(since.Add(ttl)) {
+				delete(c.active, g)
+			}
+		}
+	}
+	if _, ok := c.active[group]; ok {
+		return true
+	}
+	if max > 0 && len(c.active) >= max {
+		return false
+	}
+	c.active[group] = now
+	return true
+}
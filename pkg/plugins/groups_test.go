@@ -0,0 +1,165 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+func TestNew_ReconstructsGroupCounts(t *testing.T) {
+	objs := []runtime.Object{}
+	for i := 0; i < 3; i++ {
+		objs = append(objs, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("g1-pod%d", i),
+				Namespace: "default",
+				UID:       types.UID(fmt.Sprintf("g1-uid%d", i)),
+				Labels:    map[string]string{"podGroup": "g1"},
+			},
+		})
+	}
+	objs = append(objs, &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "g2-pod0",
+			Namespace: "default",
+			UID:       "g2-uid0",
+			Labels:    map[string]string{"podGroup": "g2"},
+		},
+	})
+
+	client := clientsetfake.NewSimpleClientset(objs...)
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	registeredPlugins := []st.RegisterPluginFunc{
+		st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}
+	fh, err := st.NewFramework(
+		registeredPlugins,
+		"default-scheduler",
+		wait.NeverStop,
+		frameworkruntime.WithClientSet(client),
+		frameworkruntime.WithInformerFactory(informerFactory),
+	)
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+
+	p, err := New(nil, fh)
+	if err != nil {
+		t.Fatalf("fail to create plugin: %s", err)
+	}
+	cs := p.(*CustomScheduler)
+	defer cs.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	informerFactory.Start(ctx.Done())
+	informerFactory.WaitForCacheSync(ctx.Done())
+
+	err = wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		return cs.groups.count("g1") == 3 && cs.groups.count("g2") == 1, nil
+	})
+	if err != nil {
+		t.Fatalf("expected counts 3 and 1, got %d and %d", cs.groups.count("g1"), cs.groups.count("g2"))
+	}
+
+	// replaying the same pods must not change the counts
+	for _, obj := range objs {
+		cs.groups.add(obj.(*v1.Pod))
+	}
+	if got := cs.groups.count("g1"); got != 3 {
+		t.Errorf("expected count 3 after replay, got %d", got)
+	}
+}
+
+func TestNew_SyncedOnceInformersStart(t *testing.T) {
+	client := clientsetfake.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod0",
+			Namespace: "default",
+			Labels:    map[string]string{"podGroup": "g1"},
+		},
+	})
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	registeredPlugins := []st.RegisterPluginFunc{
+		st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}
+	fh, err := st.NewFramework(
+		registeredPlugins,
+		"default-scheduler",
+		wait.NeverStop,
+		frameworkruntime.WithClientSet(client),
+		frameworkruntime.WithInformerFactory(informerFactory),
+	)
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+
+	p, err := New(nil, fh)
+	if err != nil {
+		t.Fatalf("fail to create plugin: %s", err)
+	}
+	cs := p.(*CustomScheduler)
+	defer cs.Close()
+
+	// the shared factory is the scheduler's to start
+	podInformer := informerFactory.Core().V1().Pods()
+	if cs.HasSynced() {
+		t.Fatalf("expected the plugin not synced before the informers start")
+	}
+	stopInformers := startInformers(t, fh)
+	defer stopInformers()
+	if !cs.HasSynced() {
+		t.Fatalf("expected the plugin synced once the informers are")
+	}
+	pods, err := podInformer.Lister().List(labels.Everything())
+	if err != nil {
+		t.Fatalf("fail to list pods: %s", err)
+	}
+	if len(pods) != 1 {
+		t.Errorf("expected 1 pod in the cache, got %d", len(pods))
+	}
+}
+
+func TestGroupCounter_UpdateKeepsGroupState(t *testing.T) {
+	c := newGroupCounter(func(pod *v1.Pod) (string, bool) {
+		group, ok := pod.Labels["podGroup"]
+		return group, ok
+	})
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod0", UID: "uid0", Labels: map[string]string{"podGroup": "g1"}}}
+	c.add(pod)
+	c.setReady("g1", true)
+	if !c.activate("g1", 1, time.Now(), 0) {
+		t.Fatalf("expected g1 to take the only slot")
+	}
+
+	updated := pod.DeepCopy()
+	updated.Status.Phase = v1.PodPending
+	c.update(pod, updated)
+	if c.setReady("g1", true) {
+		t.Errorf("expected g1 to stay ready across an update")
+	}
+	if c.activate("g2", 1, time.Now(), 0) {
+		t.Errorf("expected g1 to keep its slot across an update")
+	}
+
+	c.remove(updated)
+	if !c.activate("g2", 1, time.Now(), 0) {
+		t.Errorf("expected the slot to free up once g1 is gone")
+	}
+}
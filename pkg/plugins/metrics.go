@@ -0,0 +1,86 @@
+package plugins
+
+import (
+	"log"
+	"sync"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// scoreClampedTotal counts the node scores NormalizeScore had to clamp into
+// the framework's range, which points at a bug in Score.
+var scoreClampedTotal = metrics.NewCounter(
+	&metrics.CounterOpts{
+		Name:           "customscheduler_score_clamped_total",
+		Help:           "Number of node scores clamped into the valid range by NormalizeScore.",
+		StabilityLevel: metrics.ALPHA,
+	},
+)
+
+// gangSizeRatio observes how close a group is to its minimum each time
+// PreFilter checks it, as the ratio of present to required members. Rejected
+// gangs with ratios near one are almost ready.
+var gangSizeRatio = metrics.NewHistogramVec(
+	&metrics.HistogramOpts{
+		Name:           "customscheduler_gang_size_ratio",
+		Help:           "Ratio of present to required group members observed by PreFilter, by result.",
+		Buckets:        []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1, 1.5, 2},
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"result"},
+)
+
+// listerBreakerTripsTotal counts the times repeated lister errors turned gang
+// gating off.
+var listerBreakerTripsTotal = metrics.NewCounter(
+	&metrics.CounterOpts{
+		Name:           "customscheduler_lister_breaker_trips_total",
+		Help:           "Number of times consecutive lister errors turned gang gating off.",
+		StabilityLevel: metrics.ALPHA,
+	},
+)
+
+// gangChecksTotal counts the gang size checks of PreFilter by group, as
+// reported by GroupNameNormalizer, and result. Groups the normalizer
+// doesn't match, or every group without one, are counted as
+// unnormalizedGroup, raw group names would make the label set grow with
+// every gang.
+var gangChecksTotal = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Name:           "customscheduler_gang_checks_total",
+		Help:           "Number of group size checks by PreFilter, by group and result.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"group", "result"},
+)
+
+// Results of the gang size check.
+const (
+	gangRejected string = "rejected"
+	gangPassed   string = "passed"
+)
+
+var registerMetricsOnce sync.Once
+
+// registerMetrics registers the plugin metrics with the registry the
+// scheduler serves on /metrics. It is safe to call more than once.
+func registerMetrics() {
+	registerMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(scoreClampedTotal, gangSizeRatio, gangChecksTotal, listerBreakerTripsTotal)
+	})
+}
+
+// observeGangSize logs how far the group is from its minimum and records the
+// check and the ratio, groups without a minimum have no meaningful ratio.
+func (cs *CustomScheduler) observeGangSize(group string, observed, required int) {
+	result := gangPassed
+	if observed < required {
+		result = gangRejected
+	}
+	log.Printf("Group %s %s the size check, %d present, minimum required is %d.", group, result, observed, required)
+	gangChecksTotal.WithLabelValues(cs.metricGroupName(group), result).Inc()
+	if required > 0 {
+		gangSizeRatio.WithLabelValues(result).Observe(float64(observed) / float64(required))
+	}
+}
@@ -0,0 +1,100 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ScoreMode is the way Score ranks nodes.
+type ScoreMode string
+
+const (
+	leastMode    ScoreMode = "Least"
+	mostMode     ScoreMode = "Most"
+	binPackMode  ScoreMode = "BinPack"
+	neutralMode  ScoreMode = "Neutral"
+	blendMode    ScoreMode = "Blend"
+	balancedMode ScoreMode = "Balanced"
+	dominantMode ScoreMode = "DominantResource"
+)
+
+// Modes scoring by resources other than memory.
+const (
+	leastEphemeralStorageMode ScoreMode = "LeastEphemeralStorage"
+	mostEphemeralStorageMode  ScoreMode = "MostEphemeralStorage"
+	leastPodHeadroomMode      ScoreMode = "LeastPodHeadroom"
+	mostPodHeadroomMode       ScoreMode = "MostPodHeadroom"
+	leastAnnotationMode       ScoreMode = "LeastAnnotation"
+	mostAnnotationMode        ScoreMode = "MostAnnotation"
+	leastGPUMemoryMode        ScoreMode = "LeastGPUMemory"
+	mostGPUMemoryMode         ScoreMode = "MostGPUMemory"
+)
+
+// knownModes lists the modes the plugin knows how to score.
+var knownModes = []ScoreMode{
+	leastMode, mostMode, binPackMode, neutralMode, blendMode, balancedMode, leastEphemeralStorageMode, mostEphemeralStorageMode,
+	leastPodHeadroomMode, mostPodHeadroomMode, leastAnnotationMode, mostAnnotationMode, dominantMode,
+	leastGPUMemoryMode, mostGPUMemoryMode,
+}
+
+// scoresRawQuantity reports whether the mode scores raw quantities, e.g.
+// bytes or annotation values, rather than ratios or small counts. Without
+// NormalizeScore every node saturates the framework's range under them.
+func scoresRawQuantity(mode ScoreMode) bool {
+	switch mode {
+	case leastMode, mostMode, blendMode, leastEphemeralStorageMode, mostEphemeralStorageMode, leastPodHeadroomMode,
+		leastAnnotationMode, mostAnnotationMode, leastGPUMemoryMode, mostGPUMemoryMode:
+		return true
+	}
+	return false
+}
+
+// allModes lists the built-in modes followed by the registered ones.
+func allModes() []ScoreMode {
+	return append(append([]ScoreMode{}, knownModes...), registeredModes()...)
+}
+
+func (m ScoreMode) String() string {
+	return string(m)
+}
+
+// ParseMode trims the mode and matches it to a known mode, built in or
+// registered, regardless of case, e.g. "least" parses as Least.
+func ParseMode(mode string) (ScoreMode, error) {
+	trimmed := strings.TrimSpace(mode)
+	for _, known := range allModes() {
+		if strings.EqualFold(trimmed, known.String()) {
+			return known, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q, accepted modes are %s", ErrInvalidMode, mode, acceptedModes())
+}
+
+// isValidMode reports whether the mode is one the plugin knows how to score.
+func isValidMode(mode ScoreMode) bool {
+	for _, known := range allModes() {
+		if mode == known {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalMode returns the known mode the configured one parses as, unknown
+// modes are returned as given so Validate can reject them.
+func canonicalMode(mode ScoreMode) ScoreMode {
+	if parsed, err := ParseMode(mode.String()); err == nil {
+		return parsed
+	}
+	return mode
+}
+
+// acceptedModes lists the known modes for error messages.
+func acceptedModes() string {
+	modes := allModes()
+	names := make([]string, 0, len(modes))
+	for _, known := range modes {
+		names = append(names, known.String())
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,55 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    ScoreMode
+		wantErr bool
+	}{
+		{mode: "Least", want: leastMode},
+		{mode: "most", want: mostMode},
+		{mode: " BINPACK ", want: binPackMode},
+		{mode: "dominantresource", want: dominantMode},
+		{mode: "MostGPUMemory", want: mostGPUMemoryMode},
+		{mode: "", wantErr: true},
+		{mode: "Spread", wantErr: true},
+		{mode: "Least Most", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got, err := ParseMode(tt.mode)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected mode %q to be rejected, got %s", tt.mode, got)
+				}
+				if !strings.Contains(err.Error(), "accepted modes are Least, Most") {
+					t.Errorf("expected the error to list the accepted modes, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected mode %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestScoreMode_String(t *testing.T) {
+	for _, mode := range knownModes {
+		parsed, err := ParseMode(mode.String())
+		if err != nil {
+			t.Fatalf("unexpected error parsing %s: %v", mode, err)
+		}
+		if parsed != mode {
+			t.Errorf("expected %s to round-trip, got %s", mode, parsed)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package plugins
+
+import (
+	"fmt"
+	"log"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// startAllocatableOverrides watches the ConfigMap mapping node names to the
+// allocatable memory Score pretends they have, e.g. "node-1: 64Gi". It's meant
+// for simulating bigger nodes in test environments, the real allocatable is
+// still what the other plugins and the kubelet go by. Only the ConfigMap
+// itself is watched, by an informer the plugin owns and stops when closed.
+func (cs *CustomScheduler) startAllocatableOverrides(h framework.Handle) error {
+	namespace, name := cs.allocatableOverrideNamespace, cs.allocatableOverrideName
+	if h.ClientSet() == nil {
+		return fmt.Errorf("error watching allocatable overrides %s/%s: no clientset", namespace, name)
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(h.ClientSet(), 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	configMaps := factory.Core().V1().ConfigMaps()
+	informer := configMaps.Informer()
+	cs.allocatableOverrides = configMaps.Lister().ConfigMaps(namespace)
+	cs.allocatableOverridesSynced = informer.HasSynced
+
+	// New doesn't wait for the sync, nodes are scored by their real
+	// allocatable until it's done and HasSynced reports it
+	factory.Start(cs.stopCh)
+	return nil
+}
+
+// allocatableOverride returns the memory the override ConfigMap assigns to
+// the node, ok is false when the node isn't overridden.
+func (cs *CustomScheduler) allocatableOverride(nodeName string) (int64, bool) {
+	if cs.allocatableOverrides == nil {
+		return 0, false
+	}
+	cm, err := cs.allocatableOverrides.Get(cs.allocatableOverrideName)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Warning: error reading allocatable overrides %s: %v", cs.allocatableOverrideName, err)
+		}
+		return 0, false
+	}
+	raw, ok := cm.Data[nodeName]
+	if !ok {
+		return 0, false
+	}
+	q, err := resource.ParseQuantity(raw)
+	if err != nil || q.Sign() < 0 {
+		log.Printf("Warning: invalid allocatable override %q for node %s in %s/%s, scoring its real allocatable.", raw, nodeName, cm.Namespace, cm.Name)
+		return 0, false
+	}
+	return q.Value(), true
+}
@@ -0,0 +1,58 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+func TestCustomScheduler_ScoreAllocatableOverrides(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 200),
+		makeNodeInfo("m3", 1000, 300),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	overrides := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "overrides", Namespace: "kube-system"},
+		// m2 isn't a valid quantity, so it keeps its real allocatable
+		Data: map[string]string{"m1": "400", "m2": "lots"},
+	}
+	if _, err := fh.ClientSet().CoreV1().ConfigMaps("kube-system").Create(context.Background(), overrides, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("fail to create ConfigMap: %s", err)
+	}
+
+	p, err := New(&runtime.Unknown{Raw: []byte(`{"mode": "Most", "allocatableOverrideConfigMap": "overrides", "allocatableOverrideNamespace": "kube-system"}`)}, fh)
+	if err != nil {
+		t.Fatalf("fail to create plugin: %s", err)
+	}
+	cs := p.(*CustomScheduler)
+	stopInformers := startInformers(t, fh)
+	defer func() {
+		cs.Close()
+		stopInformers()
+	}()
+	// New doesn't wait for the overrides to sync
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) { return cs.HasSynced(), nil }); err != nil {
+		t.Fatalf("expected the plugin synced with the overrides watched: %v", err)
+	}
+
+	pod := st.MakePod().Name("pod1").Namespace("default").Phase(v1.PodPending).Obj()
+	state := framework.NewCycleState()
+	want := map[string]int64{"m1": 100, "m2": 0, "m3": 50}
+	for _, s := range scoreNodes(t, cs, state, pod, nodeInfos) {
+		if s.Score != want[s.Name] {
+			t.Errorf("expected node %s to score %d, got %d", s.Name, want[s.Name], s.Score)
+		}
+	}
+	if got := nodeInfos[0].Allocatable.Memory; got != 100 {
+		t.Errorf("expected the snapshot left as is, got allocatable memory %d on m1", got)
+	}
+}
@@ -0,0 +1,84 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ framework.PermitPlugin = &CustomScheduler{}
+
+// The phases GangGatePhase accepts.
+const (
+	prefilterGatePhase = "prefilter"
+	permitGatePhase    = "permit"
+)
+
+// permitTimeout is how long a member waits at Permit for the rest of its
+// gang before the framework rejects it.
+const permitTimeout = 60 * time.Second
+
+// Permit invoked at the permit extension point. With GangGatePhase permit,
+// the members of a gang wait here until enough of them, and enough of each
+// role in minAvailableByRole, are placed, then all of them are let through
+// together.
+func (cs *CustomScheduler) Permit(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
+	if cs.gangGatePhase != permitGatePhase || cs.skipGangGating || cs.softGang {
+		return nil, 0
+	}
+	if cs.excluded(pod.Namespace) || (cs.skipDaemonSetPods && isDaemonSetOrMirrorPod(pod)) {
+		return nil, 0
+	}
+	if cs.handle.SharedInformerFactory() == nil {
+		return nil, 0
+	}
+
+	groupLabel, pods, exists, err := cs.groupMembers(pod)
+	if err != nil {
+		return framework.AsStatus(err), 0
+	}
+	if !exists {
+		return nil, 0
+	}
+	minAvailable, byRole, err := cs.gangMinimum(pod)
+	if err != nil {
+		return framework.AsStatus(err), 0
+	}
+
+	// the pod itself, plus the members already bound or waiting
+	placed := []*v1.Pod{pod}
+	for _, p := range pods {
+		if p.UID != pod.UID && p.Spec.NodeName != "" {
+			placed = append(placed, p)
+		}
+	}
+	var waiting []framework.WaitingPod
+	cs.handle.IterateOverWaitingPods(func(wp framework.WaitingPod) {
+		p := wp.GetPod()
+		if p.UID == pod.UID {
+			return
+		}
+		if group, ok := cs.groupName(p); ok && group == groupLabel {
+			waiting = append(waiting, wp)
+			placed = append(placed, p)
+		}
+	})
+
+	if len(placed) < minAvailable {
+		log.Printf("Pod %s waits on node %s for group %s, %d placed, minimum required is %d.", pod.Name, nodeName, groupLabel, len(placed), minAvailable)
+		return framework.NewStatus(framework.Wait, fmt.Sprintf("Not enough pods placed in group %s, %d placed, minimum required is %d", groupLabel, len(placed), minAvailable)), permitTimeout
+	}
+	// every placed pod counts, the role minimums hold like at PreFilter
+	if role, count, roleMin, short := roleShortfall(placed, byRole, func(pods []*v1.Pod) int { return len(pods) }); short {
+		log.Printf("Pod %s waits on node %s for role %s in group %s, %d placed, minimum required is %d.", pod.Name, nodeName, role, groupLabel, count, roleMin)
+		return framework.NewStatus(framework.Wait, fmt.Sprintf("Not enough pods with role %s placed in group %s, %d placed, minimum required is %d", role, groupLabel, count, roleMin)), permitTimeout
+	}
+	for _, wp := range waiting {
+		wp.Allow(cs.Name())
+	}
+	return nil, 0
+}
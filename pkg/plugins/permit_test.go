@@ -0,0 +1,105 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+func TestCustomScheduler_GangGatePhase(t *testing.T) {
+	pod := st.MakePod().Name("pod1").UID("pod1").Namespace("default").Label("podGroup", "A").Label("minAvailable", "2").Obj()
+	tests := []struct {
+		name              string
+		phase             string
+		wantPreFilterCode framework.Code
+		wantPermitCode    framework.Code
+	}{
+		{name: "default gates at prefilter", wantPreFilterCode: framework.Unschedulable, wantPermitCode: framework.Success},
+		{name: "prefilter", phase: prefilterGatePhase, wantPreFilterCode: framework.Unschedulable, wantPermitCode: framework.Success},
+		{name: "permit", phase: permitGatePhase, wantPreFilterCode: framework.Success, wantPermitCode: framework.Wait},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, nil, []*v1.Pod{pod})
+			cs := &CustomScheduler{handle: fh, gangGatePhase: tt.phase}
+
+			_, status := cs.PreFilter(context.Background(), framework.NewCycleState(), pod)
+			if got := status.Code(); got != tt.wantPreFilterCode {
+				t.Errorf("expected PreFilter code %v, got %v", tt.wantPreFilterCode, status)
+			}
+			status, timeout := cs.Permit(context.Background(), framework.NewCycleState(), pod, "m1")
+			if got := status.Code(); got != tt.wantPermitCode {
+				t.Errorf("expected Permit code %v, got %v", tt.wantPermitCode, status)
+			}
+			if tt.wantPermitCode == framework.Wait && timeout != permitTimeout {
+				t.Errorf("expected Permit to wait %v, got %v", permitTimeout, timeout)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PermitCountsBoundMembers(t *testing.T) {
+	bound := st.MakePod().Name("pod1").UID("pod1").Namespace("default").Label("podGroup", "A").Label("minAvailable", "2").Node("m1").Obj()
+	pod := st.MakePod().Name("pod2").UID("pod2").Namespace("default").Label("podGroup", "A").Label("minAvailable", "2").Obj()
+	fh := newTestHandle(t, nil, []*v1.Pod{bound, pod})
+	cs := &CustomScheduler{handle: fh, gangGatePhase: permitGatePhase}
+
+	if status, _ := cs.Permit(context.Background(), framework.NewCycleState(), pod, "m2"); !status.IsSuccess() {
+		t.Errorf("expected the pod completing the gang to be permitted, got %v", status)
+	}
+}
+
+func TestCustomScheduler_PermitExcludedNamespace(t *testing.T) {
+	pod := st.MakePod().Name("coredns").UID("coredns").Namespace("kube-system").Label("podGroup", "A").Label("minAvailable", "2").Obj()
+	fh := newTestHandle(t, nil, []*v1.Pod{pod})
+	cs := &CustomScheduler{handle: fh, gangGatePhase: permitGatePhase, excludedNamespaces: namespaceSet(defaultExcludedNamespaces)}
+
+	if status, _ := cs.Permit(context.Background(), framework.NewCycleState(), pod, "m1"); !status.IsSuccess() {
+		t.Errorf("expected the pod of an excluded namespace permitted, got %v", status)
+	}
+}
+
+func TestCustomScheduler_PermitAllowsWaitingGang(t *testing.T) {
+	pod1 := st.MakePod().Name("pod1").UID("pod1").Namespace("default").Label("podGroup", "A").Label("minAvailable", "2").Obj()
+	pod2 := st.MakePod().Name("pod2").UID("pod2").Namespace("default").Label("podGroup", "A").Label("minAvailable", "2").Obj()
+	factory := func(_ runtime.Object, h framework.Handle) (framework.Plugin, error) {
+		return &CustomScheduler{handle: h, gangGatePhase: permitGatePhase}, nil
+	}
+	fwk := newTestFramework(t, []st.RegisterPluginFunc{st.RegisterPermitPlugin(Name, factory)}, nil, []*v1.Pod{pod1, pod2})
+
+	ctx := context.Background()
+	if status := fwk.RunPermitPlugins(ctx, framework.NewCycleState(), pod1, "m1"); status.Code() != framework.Wait {
+		t.Fatalf("expected the first member to wait, got %v", status)
+	}
+	if status := fwk.RunPermitPlugins(ctx, framework.NewCycleState(), pod2, "m2"); !status.IsSuccess() {
+		t.Fatalf("expected the member completing the gang to be permitted, got %v", status)
+	}
+	if status := fwk.WaitOnPermit(ctx, pod1); !status.IsSuccess() {
+		t.Errorf("expected the waiting member to be allowed, got %v", status)
+	}
+}
+
+func TestCustomScheduler_PermitWaitsOnShortRole(t *testing.T) {
+	byRole := `{"driver": 1, "worker": 1}`
+	worker1 := st.MakePod().Name("worker1").UID("worker1").Namespace("default").Label("podGroup", "A").Label("podRole", "worker").
+		Annotation("minAvailableByRole", byRole).Node("m1").Obj()
+	worker2 := st.MakePod().Name("worker2").UID("worker2").Namespace("default").Label("podGroup", "A").Label("podRole", "worker").
+		Annotation("minAvailableByRole", byRole).Obj()
+	driver := st.MakePod().Name("driver").UID("driver").Namespace("default").Label("podGroup", "A").Label("podRole", "driver").
+		Annotation("minAvailableByRole", byRole).Obj()
+	fh := newTestHandle(t, nil, []*v1.Pod{worker1, worker2, driver})
+	cs := &CustomScheduler{handle: fh, gangGatePhase: permitGatePhase}
+
+	// two placed pods meet the gang minimum, but no driver is among them
+	status, timeout := cs.Permit(context.Background(), framework.NewCycleState(), worker2, "m2")
+	if status.Code() != framework.Wait || timeout != permitTimeout {
+		t.Errorf("expected the second worker to wait for a driver, got %v after %v", status, timeout)
+	}
+	if status, _ := cs.Permit(context.Background(), framework.NewCycleState(), driver, "m2"); !status.IsSuccess() {
+		t.Errorf("expected the driver completing its role to be permitted, got %v", status)
+	}
+}
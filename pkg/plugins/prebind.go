@@ -0,0 +1,117 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	modeAnnotation            string = "customscheduler.example.com/mode"
+	nodeAllocMemoryAnnotation string = "customscheduler.example.com/node-alloc-memory"
+	// nodeScoreAnnotation holds the raw score the chosen node got.
+	nodeScoreAnnotation string = "scheduling.nthu/nodeScore"
+)
+
+var _ framework.PreBindPlugin = &CustomScheduler{}
+
+// PreBind reconfirms that the pod's group still meets minAvailable, then
+// records the scheduling decision on the pod before it is bound, so the mode
+// and the node's allocatable memory, along with how the node was scored, can
+// be audited later.
+func (cs *CustomScheduler) PreBind(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	log.Printf("Pod %s is in PreBind phase. Annotate the decision for Node %s.", pod.Name, nodeName)
+
+	// members may have been deleted since PreFilter, don't commit a half gang
+	if status := cs.confirmGang(pod); !status.IsSuccess() {
+		return status
+	}
+
+	// the snapshot may not be wired up yet while the scheduler warms up
+	lister := cs.handle.SnapshotSharedLister()
+	if lister == nil {
+		return framework.AsStatus(fmt.Errorf("snapshot lister unavailable, can't annotate pod %s for node %s", pod.Name, nodeName))
+	}
+	nodeinfo, err := lister.NodeInfos().Get(nodeName)
+	if err != nil {
+		return framework.AsStatus(fmt.Errorf("nodeInfo not found on node %s", nodeName))
+	}
+
+	annotations := map[string]string{
+		modeAnnotation: cs.modeFor(pod).String(),
+	}
+	// nodes that just registered may not report allocatable yet
+	if nodeinfo.Allocatable != nil {
+		annotations[nodeAllocMemoryAnnotation] = strconv.FormatInt(memoryScore(nodeinfo), 10)
+	}
+	if score, ok := ReadScore(state, nodeName); ok {
+		annotations[nodeScoreAnnotation] = strconv.FormatInt(score, 10)
+	}
+	if explanation, ok := explanationFor(state, nodeName); ok {
+		annotations[explanationAnnotation] = explanation
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return framework.AsStatus(fmt.Errorf("error building annotation patch for pod %s: %v", pod.Name, err))
+	}
+
+	// the pod may be updated concurrently, e.g. by its controller
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		_, err := cs.handle.ClientSet().CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	if err != nil {
+		return framework.AsStatus(fmt.Errorf("error annotating pod %s: %v", pod.Name, err))
+	}
+
+	return framework.NewStatus(framework.Success)
+}
+
+// confirmGang recounts the live members of the pod's group and rejects the
+// pod with a retriable status if the group dropped below minAvailable, or
+// one of its roles below the role's minimum.
+func (cs *CustomScheduler) confirmGang(pod *v1.Pod) *framework.Status {
+	if cs.skipGangGating || cs.softGang || cs.excluded(pod.Namespace) || (cs.skipDaemonSetPods && isDaemonSetOrMirrorPod(pod)) {
+		return nil
+	}
+	// PreFilter let the pod through without gang gating, don't recount
+	if cs.breaker.isOpen(cs.now()) {
+		return nil
+	}
+	groupLabel, pods, exists, err := cs.groupMembers(pod)
+	if err != nil {
+		return cs.listerFailed(err)
+	}
+	if !exists {
+		return nil
+	}
+	// the same minimum PreFilter let the pod through with
+	minAvailable, byRole, err := cs.gangMinimum(pod)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	count, err := cs.groupCount(pod, groupLabel, pods)
+	if err != nil {
+		return cs.listerFailed(err)
+	}
+	cs.breaker.success()
+	if count < minAvailable {
+		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Group %s dropped to %d pods before binding, minimum required is %d", groupLabel, count, minAvailable))
+	}
+	if role, roleCount, roleMin, short := cs.shortRole(pods, byRole); short {
+		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Role %s of group %s dropped to %d pods before binding, minimum required is %d", role, groupLabel, roleCount, roleMin))
+	}
+	return nil
+}
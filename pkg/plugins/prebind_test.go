@@ -0,0 +1,234 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+func TestCustomScheduler_PreBind(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod0", Namespace: "default"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{}},
+	}
+	client := clientsetfake.NewSimpleClientset(pod)
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	registeredPlugins := []st.RegisterPluginFunc{
+		st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}
+	fh, err := st.NewFramework(
+		registeredPlugins,
+		"default-scheduler",
+		wait.NeverStop,
+		frameworkruntime.WithClientSet(client),
+		frameworkruntime.WithInformerFactory(informerFactory),
+		frameworkruntime.WithSnapshotSharedLister(&fakeSharedLister{nodes: []*framework.NodeInfo{makeNodeInfo("m1", 1000, 200)}}),
+	)
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+
+	cs := &CustomScheduler{
+		handle:    fh,
+		scoreMode: mostMode,
+	}
+
+	if status := cs.PreBind(context.Background(), nil, pod, "m1"); !status.IsSuccess() {
+		t.Fatalf("unexpected error: %v", status)
+	}
+
+	got, err := client.CoreV1().Pods("default").Get(context.Background(), "pod0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("fail to get pod: %s", err)
+	}
+	want := map[string]string{
+		modeAnnotation:            mostMode.String(),
+		nodeAllocMemoryAnnotation: "200",
+	}
+	for k, v := range want {
+		if got.Annotations[k] != v {
+			t.Errorf("expected annotation %s=%s, got %q", k, v, got.Annotations[k])
+		}
+	}
+}
+
+func TestCustomScheduler_PreBindWarmingUp(t *testing.T) {
+	fh := warmingUpHandle{Handle: newTestHandle(t, nil, nil)}
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode}
+	pod := st.MakePod().Name("pod0").Namespace("default").Obj()
+
+	status := cs.PreBind(context.Background(), nil, pod, "m1")
+	if status.Code() != framework.Error {
+		t.Errorf("expected an error without a snapshot lister, got %v", status)
+	}
+}
+
+func TestCustomScheduler_PreBindNodeScore(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod0", Namespace: "default"},
+	}
+	nodeInfos := []*framework.NodeInfo{makeNodeInfo("m1", 1000, 200), makeNodeInfo("m2", 1000, 100)}
+	fh := newTestHandle(t, nodeInfos, []*v1.Pod{pod})
+	// the first patch races with another update of the pod
+	client := fh.ClientSet().(*clientsetfake.Clientset)
+	conflicts := 0
+	client.PrependReactor("patch", "pods", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			return false, nil, nil
+		}
+		conflicts++
+		return true, nil, apierrors.NewConflict(v1.Resource("pods"), pod.Name, fmt.Errorf("the object has been modified"))
+	})
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode}
+
+	state := framework.NewCycleState()
+	if status := cs.PreScore(context.Background(), state, pod, []*v1.Node{nodeInfos[0].Node(), nodeInfos[1].Node()}); !status.IsSuccess() {
+		t.Fatalf("unexpected PreScore error: %v", status)
+	}
+	scoreNodes(t, cs, state, pod, nodeInfos)
+	if status := cs.PreBind(context.Background(), state, pod, "m1"); !status.IsSuccess() {
+		t.Fatalf("unexpected error: %v", status)
+	}
+
+	got, err := client.CoreV1().Pods("default").Get(context.Background(), "pod0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("fail to get pod: %s", err)
+	}
+	if got.Annotations[nodeScoreAnnotation] != "200" {
+		t.Errorf("expected annotation %s=200, got %q", nodeScoreAnnotation, got.Annotations[nodeScoreAnnotation])
+	}
+	if conflicts != 1 {
+		t.Errorf("expected the conflicting patch retried, got %d conflicts", conflicts)
+	}
+}
+
+func TestCustomScheduler_PreBindConfirmsGang(t *testing.T) {
+	newPod := func(name string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels: map[string]string{
+					"podGroup":     "g1",
+					"minAvailable": "3",
+				},
+			},
+		}
+	}
+	members := []*v1.Pod{newPod("pod0"), newPod("pod1"), newPod("pod2")}
+	client := clientsetfake.NewSimpleClientset(members[0])
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	registeredPlugins := []st.RegisterPluginFunc{
+		st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}
+	fh, err := st.NewFramework(
+		registeredPlugins,
+		"default-scheduler",
+		wait.NeverStop,
+		frameworkruntime.WithClientSet(client),
+		frameworkruntime.WithInformerFactory(informerFactory),
+		frameworkruntime.WithSnapshotSharedLister(&fakeSharedLister{nodes: []*framework.NodeInfo{makeNodeInfo("m1", 1000, 200)}}),
+	)
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+
+	cs := &CustomScheduler{
+		handle:    fh,
+		scoreMode: mostMode,
+	}
+	for _, p := range members {
+		podInformer.Informer().GetStore().Add(p)
+	}
+
+	if _, status := cs.PreFilter(context.Background(), nil, members[0]); !status.IsSuccess() {
+		t.Fatalf("expected PreFilter to pass, got %v", status)
+	}
+
+	// a member goes away before the pod is bound
+	podInformer.Informer().GetStore().Delete(members[2])
+
+	status := cs.PreBind(context.Background(), nil, members[0], "m1")
+	if status.Code() != framework.Unschedulable {
+		t.Errorf("expected %v, got %v", framework.Unschedulable, status.Code())
+	}
+	got, err := client.CoreV1().Pods("default").Get(context.Background(), "pod0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("fail to get pod: %s", err)
+	}
+	if _, ok := got.Annotations[modeAnnotation]; ok {
+		t.Errorf("expected a rejected pod not to be annotated")
+	}
+}
+
+func TestCustomScheduler_PreBindExcludedNamespace(t *testing.T) {
+	// a lone member of a gang of 3, labeled by mistake
+	pod := st.MakePod().Name("coredns").Namespace("kube-system").Label("podGroup", "g1").Label("minAvailable", "3").Obj()
+	fh := newTestHandle(t, []*framework.NodeInfo{makeNodeInfo("m1", 1000, 200)}, []*v1.Pod{pod})
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode, excludedNamespaces: namespaceSet(defaultExcludedNamespaces)}
+
+	if _, status := cs.PreFilter(context.Background(), nil, pod); !status.IsSuccess() {
+		t.Fatalf("expected PreFilter to pass, got %v", status)
+	}
+	if status := cs.PreBind(context.Background(), nil, pod, "m1"); !status.IsSuccess() {
+		t.Errorf("expected PreBind to pass, got %v", status)
+	}
+}
+
+func TestCustomScheduler_PreBindRoleGang(t *testing.T) {
+	member := func(name, role string) *v1.Pod {
+		return st.MakePod().Name(name).Namespace("default").Label("podGroup", "job1").Label("podRole", role).
+			Annotation("minAvailableByRole", `{"driver": 1, "worker": 1}`).Obj()
+	}
+	pods := []*v1.Pod{member("driver-0", "driver"), member("worker-0", "worker")}
+	fh := newTestHandle(t, []*framework.NodeInfo{makeNodeInfo("m1", 1000, 200)}, pods)
+	// no minAvailable label and no default, the role minimums alone
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode}
+
+	if _, status := cs.PreFilter(context.Background(), nil, pods[0]); !status.IsSuccess() {
+		t.Fatalf("expected PreFilter to pass, got %v", status)
+	}
+	if status := cs.PreBind(context.Background(), nil, pods[0], "m1"); !status.IsSuccess() {
+		t.Errorf("expected PreBind to pass, got %v", status)
+	}
+}
+
+func TestCustomScheduler_PreBindRoleDropped(t *testing.T) {
+	member := func(name, role string) *v1.Pod {
+		return st.MakePod().Name(name).Namespace("default").Label("podGroup", "job1").Label("podRole", role).
+			Annotation("minAvailableByRole", `{"driver": 1, "worker": 1}`).Obj()
+	}
+	pods := []*v1.Pod{member("worker-0", "worker"), member("worker-1", "worker"), member("driver-0", "driver")}
+	fh := newTestHandle(t, []*framework.NodeInfo{makeNodeInfo("m1", 1000, 200)}, pods)
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode}
+
+	if _, status := cs.PreFilter(context.Background(), nil, pods[0]); !status.IsSuccess() {
+		t.Fatalf("expected PreFilter to pass, got %v", status)
+	}
+	// the only driver goes away, the group keeps enough pods overall
+	if err := fh.SharedInformerFactory().Core().V1().Pods().Informer().GetStore().Delete(pods[2]); err != nil {
+		t.Fatalf("fail to delete pod: %s", err)
+	}
+	status := cs.PreBind(context.Background(), nil, pods[0], "m1")
+	want := "Role driver of group job1 dropped to 0 pods before binding, minimum required is 1"
+	if status.Code() != framework.Unschedulable || status.Message() != want {
+		t.Errorf("expected Unschedulable with %q, got %v", want, status)
+	}
+}
@@ -0,0 +1,38 @@
+package plugins
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// pressureConditions are the node conditions that make a node less
+// desirable, the kubelet may start evicting pods under either.
+var pressureConditions = []v1.NodeConditionType{v1.NodeMemoryPressure, v1.NodeDiskPressure}
+
+// nodePressure returns the first pressure condition that is true on the node
+// in the snapshot, ok is false when there is none.
+func nodePressure(node *v1.Node) (v1.NodeConditionType, bool) {
+	if node == nil {
+		return "", false
+	}
+	for _, want := range pressureConditions {
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == want && cond.Status == v1.ConditionTrue {
+				return cond.Type, true
+			}
+		}
+	}
+	return "", false
+}
+
+// pressureRejection rejects a node under pressure when pressured nodes are
+// filtered, it returns nil otherwise.
+func (cs *CustomScheduler) pressureRejection(node *v1.Node) *framework.Status {
+	if !cs.filterPressuredNodes {
+		return nil
+	}
+	if cond, ok := nodePressure(node); ok {
+		return nodeRejection(framework.UnschedulableAndUnresolvable, node.Name, "pressure", "node has %s", cond)
+	}
+	return nil
+}
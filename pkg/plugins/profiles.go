@@ -0,0 +1,69 @@
+package plugins
+
+import (
+	"log"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// scoringProfileLabel is the pod label naming the scoring profile the pod is
+// scored with.
+const scoringProfileLabel string = "scoringProfile"
+
+// ScoringProfile is a named set of scoring settings, selected per pod with
+// the scoringProfile label, so workloads wanting different modes can share
+// one scheduler profile. Unset fields keep the plugin's settings.
+type ScoringProfile struct {
+	// Mode scores the pods of the profile. A scoreMode label on the pod
+	// still takes precedence.
+	Mode ScoreMode `json:"mode"`
+	// ScoreBy overrides the memory figure the Least and Most modes score
+	// by.
+	ScoreBy string `json:"scoreBy"`
+	// CostWeight and PreferredNodeLabelsWeight override the plugin's
+	// weights when non-zero.
+	CostWeight                int64 `json:"costWeight"`
+	PreferredNodeLabelsWeight int64 `json:"preferredNodeLabelsWeight"`
+}
+
+// profileFor returns the scoring profile the pod selects, ok is false for
+// pods without the label or naming an unknown profile.
+func (cs *CustomScheduler) profileFor(pod *v1.Pod) (ScoringProfile, bool) {
+	name, exists := pod.Labels[scoringProfileLabel]
+	if !exists || len(cs.scoringProfiles) == 0 {
+		return ScoringProfile{}, false
+	}
+	profile, ok := cs.scoringProfiles[name]
+	if !ok {
+		// profileFor runs several times per node and cycle, warn once per name
+		if _, warned := cs.unknownProfiles.LoadOrStore(name, struct{}{}); !warned {
+			log.Printf("Warning: pod %s selects unknown scoring profile %q, using the plugin's settings.", pod.Name, name)
+		}
+	}
+	return profile, ok
+}
+
+// scoreByFor returns the memory figure the pod is scored by.
+func (cs *CustomScheduler) scoreByFor(pod *v1.Pod) string {
+	if profile, ok := cs.profileFor(pod); ok && profile.ScoreBy != "" {
+		return profile.ScoreBy
+	}
+	return cs.scoreBy
+}
+
+// costWeightFor returns the weight of node costs in the pod's scores.
+func (cs *CustomScheduler) costWeightFor(pod *v1.Pod) int64 {
+	if profile, ok := cs.profileFor(pod); ok && profile.CostWeight > 0 {
+		return profile.CostWeight
+	}
+	return cs.costWeight
+}
+
+// preferredWeightFor returns the preferred node labels bonus percentage of
+// the pod's scores.
+func (cs *CustomScheduler) preferredWeightFor(pod *v1.Pod) int64 {
+	if profile, ok := cs.profileFor(pod); ok && profile.PreferredNodeLabelsWeight > 0 {
+		return profile.PreferredNodeLabelsWeight
+	}
+	return cs.preferredNodeLabelsWeight
+}
@@ -0,0 +1,92 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestCustomScheduler_ScoreScoringProfiles(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfoWithLabels("m1", 1000, 100, map[string]string{"tier": "batch"}),
+		makeNodeInfo("m2", 1000, 200),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	cs := &CustomScheduler{
+		handle:                    fh,
+		scoreMode:                 leastMode,
+		preferredNodeLabels:       map[string]string{"tier": "batch"},
+		preferredNodeLabelsWeight: 10,
+		scoringProfiles: map[string]ScoringProfile{
+			"packing":  {Mode: mostMode},
+			"affinity": {PreferredNodeLabelsWeight: 50},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   map[string]int64
+	}{
+		{name: "no profile", want: map[string]int64{"m1": -90, "m2": -200}},
+		{name: "profile mode", labels: map[string]string{scoringProfileLabel: "packing"}, want: map[string]int64{"m1": 110, "m2": 200}},
+		{name: "profile weight", labels: map[string]string{scoringProfileLabel: "affinity"}, want: map[string]int64{"m1": -50, "m2": -200}},
+		{name: "unknown profile", labels: map[string]string{scoringProfileLabel: "missing"}, want: map[string]int64{"m1": -90, "m2": -200}},
+		{
+			name:   "scoreMode label over the profile",
+			labels: map[string]string{scoringProfileLabel: "packing", scoreModeLabel: leastMode.String()},
+			want:   map[string]int64{"m1": -90, "m2": -200},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod0", Labels: tt.labels}}
+			for _, ni := range nodeInfos {
+				got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected error: %v", status)
+				}
+				if got != tt.want[ni.Node().Name] {
+					t.Errorf("expected score %d on node %s, got %d", tt.want[ni.Node().Name], ni.Node().Name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestNew_ScoringProfiles(t *testing.T) {
+	p, err := New(&runtime.Unknown{Raw: []byte(`{"mode": "Least", "scoringProfiles": {"packing": {"mode": "most"}}}`)}, nil)
+	if err != nil {
+		t.Fatalf("fail to create plugin: %s", err)
+	}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod0", Labels: map[string]string{scoringProfileLabel: "packing"}}}
+	if got := p.(*CustomScheduler).modeFor(pod); got != mostMode {
+		t.Errorf("expected the profile's mode %s, got %s", mostMode, got)
+	}
+	if got := p.(*CustomScheduler).modeFor(&v1.Pod{}); got != leastMode {
+		t.Errorf("expected the plugin's mode %s without the label, got %s", leastMode, got)
+	}
+}
+
+func TestCustomScheduler_ProfileForWarnsOnce(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	cs := &CustomScheduler{scoringProfiles: map[string]ScoringProfile{"packing": {Mode: mostMode}}}
+	for _, name := range []string{"missing", "missing", "packing", "other", "missing"} {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod0", Labels: map[string]string{scoringProfileLabel: name}}}
+		cs.profileFor(pod)
+	}
+	if got := strings.Count(buf.String(), "unknown scoring profile"); got != 2 {
+		t.Errorf("expected one warning per unknown profile, got %d:\n%s", got, buf.String())
+	}
+}
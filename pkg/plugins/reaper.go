@@ -0,0 +1,85 @@
+package plugins
+
+import (
+	"context"
+	"log"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// gangTimedOutReason is the reason of the events recorded on the pending
+// members of a group that stayed incomplete past GangTimeoutSeconds.
+const gangTimedOutReason string = "GangTimedOut"
+
+// startGangReaper periodically looks for groups that stayed under
+// minAvailable past the gang timeout, it stops when the plugin is closed.
+func (cs *CustomScheduler) startGangReaper() {
+	go wait.Until(cs.reapStuckGangs, cs.gangTimeout/4, cs.stopCh)
+}
+
+// reapStuckGangs records an event on the pending members of every group
+// incomplete for longer than the gang timeout, and deletes them when
+// DeleteStuckGangs is set so their controller recreates them. Each stuck
+// group is reported once until it recovers.
+func (cs *CustomScheduler) reapStuckGangs() {
+	all, err := cs.handle.SharedInformerFactory().Core().V1().Pods().Lister().List(labels.Everything())
+	if err != nil {
+		log.Printf("Warning: error listing pods for stuck gangs: %v", err)
+		return
+	}
+	groups := make(map[string][]*v1.Pod)
+	for _, p := range all {
+		if group, ok := cs.groupName(p); ok {
+			groups[group] = append(groups[group], p)
+		}
+	}
+
+	stuck := make(map[string]struct{})
+	for group, members := range groups {
+		var pending []*v1.Pod
+		for _, p := range members {
+			if p.Spec.NodeName == "" && p.Status.Phase == v1.PodPending && p.DeletionTimestamp == nil {
+				pending = append(pending, p)
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		minAvailable, _, err := cs.gangMinimum(pending[0])
+		if err != nil {
+			continue
+		}
+		count, err := cs.groupCount(pending[0], group, members)
+		if err != nil || count >= minAvailable {
+			continue
+		}
+		oldest := oldestCreation(pending[0], members)
+		if oldest.IsZero() || cs.now().Sub(oldest) <= cs.gangTimeout {
+			continue
+		}
+		stuck[group] = struct{}{}
+		if _, reported := cs.stuckGangs[group]; reported {
+			continue
+		}
+		log.Printf("Warning: group %s is incomplete after %v, %d present, minimum required is %d.", group, cs.gangTimeout, count, minAvailable)
+		for _, p := range pending {
+			if recorder := cs.handle.EventRecorder(); recorder != nil {
+				recorder.Eventf(p, nil, v1.EventTypeWarning, gangTimedOutReason, "Scheduling", "Group %s is incomplete after %v, %d present, minimum required is %d", cs.reportedGroupName(group), cs.gangTimeout, count, minAvailable)
+			}
+			if !cs.deleteStuckGangs {
+				continue
+			}
+			err := cs.handle.ClientSet().CoreV1().Pods(p.Namespace).Delete(context.Background(), p.Name, metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				// retried on the next round
+				log.Printf("Warning: error deleting pod %s of stuck group %s: %v", p.Name, group, err)
+				delete(stuck, group)
+			}
+		}
+	}
+	cs.stuckGangs = stuck
+}
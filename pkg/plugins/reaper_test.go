@@ -0,0 +1,100 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/events"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func makeReaperPods(created time.Time) []*v1.Pod {
+	member := func(name, group, minAvailable string) *st.PodWrapper {
+		return st.MakePod().Name(name).Namespace("default").Label("podGroup", group).Label("minAvailable", minAvailable).
+			CreationTimestamp(metav1.NewTime(created)).Phase(v1.PodPending)
+	}
+	return []*v1.Pod{
+		member("stuck0", "stuck", "4").Obj(),
+		member("stuck1", "stuck", "4").Obj(),
+		// bound members of the stuck group stay in place
+		member("stuck2", "stuck", "4").Node("m1").Phase(v1.PodRunning).Obj(),
+		member("complete0", "complete", "2").Obj(),
+		member("complete1", "complete", "2").Obj(),
+	}
+}
+
+func TestCustomScheduler_ReapStuckGangs(t *testing.T) {
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		delete      bool
+		wantDeleted []string
+	}{
+		{name: "events only"},
+		{name: "delete stuck gangs", delete: true, wantDeleted: []string{"stuck0", "stuck1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pods := makeReaperPods(created)
+			recorder := events.NewFakeRecorder(10)
+			fh := newTestHandle(t, nil, pods, frameworkruntime.WithEventRecorder(recorder))
+			fakeClock := testingclock.NewFakePassiveClock(created.Add(30 * time.Second))
+			cs := &CustomScheduler{handle: fh, clock: fakeClock, gangTimeout: time.Minute, deleteStuckGangs: tt.delete}
+
+			cs.reapStuckGangs()
+			if got := len(recorder.Events); got != 0 {
+				t.Errorf("expected no event before the timeout, got %d", got)
+			}
+
+			fakeClock.SetTime(created.Add(2 * time.Minute))
+			cs.reapStuckGangs()
+			// reported once
+			cs.reapStuckGangs()
+			if got := len(recorder.Events); got != 2 {
+				t.Errorf("expected an event on each pending member of the stuck group, got %d", got)
+			}
+
+			deleted := make(map[string]bool)
+			for _, name := range tt.wantDeleted {
+				deleted[name] = true
+			}
+			for _, p := range pods {
+				_, err := fh.ClientSet().CoreV1().Pods("default").Get(context.Background(), p.Name, metav1.GetOptions{})
+				if got := err != nil; got != deleted[p.Name] {
+					t.Errorf("expected pod %s deleted %v, got %v", p.Name, deleted[p.Name], got)
+				}
+			}
+		})
+	}
+}
+
+func TestNew_GangReaper(t *testing.T) {
+	pods := makeReaperPods(time.Now().Add(-time.Hour))
+	fh := newTestHandle(t, nil, pods)
+	p, err := New(&runtime.Unknown{Raw: []byte(`{"mode": "Least", "gangTimeoutSeconds": 1, "deleteStuckGangs": true}`)}, fh)
+	if err != nil {
+		t.Fatalf("fail to create plugin: %s", err)
+	}
+	defer func() {
+		p.(*CustomScheduler).Close()
+		fh.SharedInformerFactory().Shutdown()
+	}()
+
+	err = wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		list, err := fh.ClientSet().CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+		return len(list.Items) == len(pods)-2, nil
+	})
+	if err != nil {
+		t.Fatalf("expected the pending members of the stuck group deleted: %v", err)
+	}
+}
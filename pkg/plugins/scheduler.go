@@ -4,62 +4,707 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/utils/clock"
 )
 
 type CustomSchedulerArgs struct {
-	Mode string `json:"mode"`
+	Mode           ScoreMode `json:"mode"`
+	ScoreCurve     string    `json:"scoreCurve"`
+	StableTiebreak bool      `json:"stableTiebreak"`
+	// HardFailAfterSeconds turns the gang rejection into
+	// UnschedulableAndUnresolvable once the oldest group member is older than
+	// this many seconds. Zero keeps retrying forever.
+	HardFailAfterSeconds int64 `json:"hardFailAfterSeconds"`
+	// PreferredNodeLabels are the labels a node must carry to receive the
+	// affinity bonus in Score.
+	PreferredNodeLabels map[string]string `json:"preferredNodeLabels"`
+	// PreferredNodeLabelsWeight is the bonus for a matching node, as a
+	// percentage of the magnitude of its resource score. Defaults to 10.
+	PreferredNodeLabelsWeight int64 `json:"preferredNodeLabelsWeight"`
+	// SkipDaemonSetPods exempts DaemonSet-owned and mirror pods from gang
+	// gating. Defaults to true.
+	SkipDaemonSetPods bool `json:"skipDaemonSetPods"`
+	// GroupStabilizationSeconds holds a group back until its oldest member
+	// has existed for this many seconds, so every member can appear first.
+	GroupStabilizationSeconds int64 `json:"groupStabilizationSeconds"`
+	// GroupBy lists the label keys whose combined values define a group.
+	// When empty, pods are grouped by the podGroup label.
+	GroupBy []string `json:"groupBy"`
+	// GroupNameLabels lists label keys to use in place of the podGroup
+	// label, tried in order, for migrating from one key to another. A pod's
+	// group is the value of the first key it carries, so pods labeled with
+	// either key join the same group. Entries may be comma-separated lists.
+	GroupNameLabels []string `json:"groupNameLabels"`
+	// ScoreBy selects the memory figure the Least and Most modes score by:
+	// "memory" (free bytes per FreeMemoryBasis, the default) or
+	// "podCapacity" (how many more copies of the incoming pod fit in the
+	// node's free memory).
+	ScoreBy string `json:"scoreBy"`
+	// GroupSelectorExpressions further scopes which group members are
+	// counted, e.g. with set-based In/NotIn expressions.
+	GroupSelectorExpressions *metav1.LabelSelector `json:"groupSelectorExpressions"`
+	// PreferredNodeLabel is a key=value label; nodes carrying it get
+	// PreferredBonus added to their raw score before normalization.
+	PreferredNodeLabel string `json:"preferredNodeLabel"`
+	PreferredBonus     int64  `json:"preferredBonus"`
+	// CountPhases lists the pod phases counted toward minAvailable. An empty
+	// string stands for pods that haven't been given a phase yet. Defaults to
+	// Pending and Running.
+	CountPhases []string `json:"countPhases"`
+	// ExcludedNamespaces lists the namespaces whose pods are never gang gated,
+	// grouped or not. Defaults to kube-system and kube-public, an empty
+	// list gates every namespace.
+	ExcludedNamespaces []string `json:"excludedNamespaces"`
+	// SkipUngrouped makes PreFilter let pods without a group through
+	// instead of failing them. Defaults to true.
+	SkipUngrouped bool `json:"skipUngrouped"`
+	// PackRatio tunes the Blend mode between spreading (0, same as Least)
+	// and packing (1, same as Most).
+	PackRatio float64 `json:"packRatio"`
+	// CostLabel names a node label holding a numeric cost, e.g. the hourly
+	// price. When set, CostWeight times the cost is subtracted from the raw
+	// score so cheaper nodes are preferred.
+	CostLabel  string `json:"costLabel"`
+	CostWeight int64  `json:"costWeight"`
+	// MaxActiveGroups caps how many groups can be between passing PreFilter
+	// and having every member bound. Members of further groups are rejected
+	// with a retriable status. Zero means no limit.
+	MaxActiveGroups int64 `json:"maxActiveGroups"`
+	// ActiveGroupTTLSeconds frees the slot of an active group that isn't
+	// fully bound this long after it took it, so gangs that pass PreFilter
+	// but are never placed don't starve the others. 300 when unset.
+	ActiveGroupTTLSeconds int64 `json:"activeGroupTTLSeconds"`
+	// MinFreeMemoryBytes is a resource quantity, e.g. "2Gi". Filter rejects
+	// nodes whose unrequested memory is below it, to leave headroom for
+	// system daemons.
+	MinFreeMemoryBytes string `json:"minFreeMemoryBytes"`
+	// GroupExcludeLabel is a label key; group pods carrying it, e.g. sidecars
+	// or debug pods, don't count toward minAvailable.
+	GroupExcludeLabel string `json:"groupExcludeLabel"`
+	// GroupByOwner groups pods by their controlling owner, e.g. a Job,
+	// instead of by labels. minAvailable is still read from the pod label.
+	GroupByOwner bool `json:"groupByOwner"`
+	// NamespaceModes overrides the mode for pods of the listed namespaces.
+	// A scoreMode label on the pod still takes precedence.
+	NamespaceModes map[string]ScoreMode `json:"namespaceModes"`
+	// ScoringProfiles are named scoring settings pods select with the
+	// scoringProfile label, overriding their namespace's mode. Pods without
+	// the label are scored with the plugin's settings.
+	ScoringProfiles map[string]ScoringProfile `json:"scoringProfiles"`
+	// CapacityCheck makes PreFilter reject a gang up front when the ready
+	// nodes can't fit the members still to be placed.
+	CapacityCheck bool `json:"capacityCheck"`
+	// ManageGangGates runs a controller that removes the
+	// scheduling.nthu/gang-hold scheduling gate from the members of a group
+	// once it reaches minAvailable. The former
+	// customscheduler.example.com/gang gate is released too.
+	ManageGangGates bool `json:"manageGangGates"`
+	// CaseInsensitiveGroups compares group label values ignoring case.
+	// Surrounding whitespace is always ignored.
+	CaseInsensitiveGroups bool `json:"caseInsensitiveGroups"`
+	// AnnotationKey is the node annotation holding the integer the
+	// LeastAnnotation and MostAnnotation modes score by.
+	AnnotationKey string `json:"annotationKey"`
+	// ComponentCaps caps the magnitude each score component contributes
+	// to a node's score, keyed by resource, cost or affinity. Components
+	// without a cap are unbounded.
+	ComponentCaps map[string]int64 `json:"componentCaps"`
+	// ScoreClamp bounds the combined score to [-ScoreClamp, ScoreClamp].
+	// Zero disables it.
+	ScoreClamp int64 `json:"scoreClamp"`
+	// MaxRawScore is an alias of ScoreClamp, Score returns at most
+	// MaxRawScore in magnitude. Setting both to different values is an
+	// error.
+	MaxRawScore int64 `json:"maxRawScore"`
+	// GangGating holds grouped pods in PreFilter until their group reaches
+	// minAvailable. When false groups only shape scoring and their pods
+	// schedule individually. Defaults to true.
+	GangGating bool `json:"gangGating"`
+	// DominantPreference picks whether the DominantResource mode prefers
+	// nodes whose dominant utilization after placement is lowest (Least, the
+	// default) or highest (Most).
+	DominantPreference ScoreMode `json:"dominantPreference"`
+	// DefaultMinAvailable is the minimum of grouped pods without a
+	// minAvailable label. Zero requires the label on every grouped pod.
+	// Defaults to 1, so such groups are always schedulable.
+	DefaultMinAvailable int `json:"defaultMinAvailable"`
+	// GPUMemoryResource names the scalar resource the LeastGPUMemory and
+	// MostGPUMemory modes score by, nodes are scored by what's left of it
+	// once the incoming pod's request is placed. Defaults to
+	// nvidia.com/gpu-memory.
+	GPUMemoryResource string `json:"gpuMemoryResource"`
+	// MinAvailableAnnotation names a pod annotation read for the minimum of
+	// pods without the minAvailable label, before DefaultMinAvailable.
+	MinAvailableAnnotation string `json:"minAvailableAnnotation"`
+	// ColdNodeWindowSeconds and ColdNodePenaltyPercent lower the raw score
+	// of nodes that became Ready less than the window ago by the percentage
+	// of its magnitude, as their image caches are likely still cold.
+	ColdNodeWindowSeconds  int64 `json:"coldNodeWindowSeconds"`
+	ColdNodePenaltyPercent int64 `json:"coldNodePenaltyPercent"`
+	// PressurePenaltyPercent lowers the raw score of nodes under
+	// MemoryPressure or DiskPressure by the percentage of its magnitude.
+	PressurePenaltyPercent int64 `json:"pressurePenaltyPercent"`
+	// FilterPressuredNodes filters nodes under MemoryPressure or
+	// DiskPressure out instead of penalizing them.
+	FilterPressuredNodes bool `json:"filterPressuredNodes"`
+	// PriorityWeighted scales the resource part of the raw score by the
+	// pod's priority factor, so urgent pods weigh placement by resources
+	// more than by cost or affinity.
+	PriorityWeighted bool `json:"priorityWeighted"`
+	// PodCountAware lowers the raw score by the fraction of the node's pod
+	// slots already taken, so crowded nodes lose to emptier ones that have
+	// less spare memory.
+	PodCountAware bool `json:"podCountAware"`
+	// SoftGang lets pods of groups under minAvailable through PreFilter,
+	// scoring them down instead: their normalized scores are scaled by the
+	// fraction of the minimum the group, or its shortest role, reached. A
+	// penalty on the raw score wouldn't survive normalization, as every
+	// node shares it. The scaling keeps this plugin's own ranking of the
+	// nodes, it weakens its say against the other score plugins, so their
+	// preferences place the pods of incomplete gangs. It requires
+	// NormalizeScores.
+	SoftGang bool `json:"softGang"`
+	// GangGatePhase is where gangs under minAvailable are held back:
+	// "prefilter" rejects their pods early, "permit" lets them through to
+	// wait at Permit until enough members are placed. Defaults to
+	// "prefilter".
+	GangGatePhase string `json:"gangGatePhase"`
+	// AllocatableOverrideConfigMap and AllocatableOverrideNamespace name a
+	// ConfigMap mapping node names to the allocatable memory Score uses in
+	// place of the reported one, e.g. "node-1: 64Gi". For testing only: it
+	// lets lower environments simulate bigger nodes, nothing but this
+	// plugin's scores sees the overrides.
+	AllocatableOverrideConfigMap string `json:"allocatableOverrideConfigMap"`
+	AllocatableOverrideNamespace string `json:"allocatableOverrideNamespace"`
+	// SimulatePlacement has the Least and Most modes score the memory left
+	// free once the incoming pod is placed, instead of the allocatable, so
+	// a node the pod barely fits on isn't rated as if it were empty. The
+	// Balanced and DominantResource modes already count the pod's CPU and
+	// memory, and so does scoreBy podCapacity.
+	SimulatePlacement bool `json:"simulatePlacement"`
+	// ListerBreakerThreshold is how many consecutive errors listing or
+	// counting group members, within ListerBreakerWindowSeconds, turn gang
+	// gating off for the next ListerBreakerWindowSeconds, so pods keep
+	// scheduling while the lister is broken. Zero keeps gating on.
+	ListerBreakerThreshold     int   `json:"listerBreakerThreshold"`
+	ListerBreakerWindowSeconds int64 `json:"listerBreakerWindowSeconds"`
+	// FreeMemoryBasis is the memory scoreBy memory counts as free:
+	// "allocatable" (the default) or "allocatableMinusRequested", which
+	// leaves out what the node's pods already request. SimulatePlacement
+	// scores allocatableMinusRequested less the incoming pod either way.
+	FreeMemoryBasis string `json:"freeMemoryBasis"`
+	// GroupZoneAffinity gives nodes in the zone most of the group's placed
+	// members run in a bonus, so a gang tends to land in one zone without
+	// being bound to it. Zones come from the topology.kubernetes.io/zone
+	// node label.
+	GroupZoneAffinity bool `json:"groupZoneAffinity"`
+	// NormalizeScores maps the raw scores of each cycle to the framework's
+	// range in NormalizeScore. Turning it off hands the framework the raw
+	// scores, clamped to [0,100], for blending with plugins that normalize
+	// on their own; it suits the modes scoring ratios, e.g. Balanced or
+	// BinPack, as the ones scoring raw quantities saturate: Validate rejects
+	// turning it off with such a mode, e.g. Least or Most, anywhere in the
+	// args, and scoreMode labels naming one fall back to the pod's mode
+	// from the args. StableTiebreak has no effect without it. Defaults to
+	// true.
+	NormalizeScores bool `json:"normalizeScores"`
+	// MinAvailableByPriority counts only the members at or above the
+	// incoming pod's priority toward minAvailable, so low-priority
+	// stragglers don't complete a high-priority gang. Pods without a
+	// priority count as priority 0. It has no effect on groups counted by
+	// a GroupCounter.
+	MinAvailableByPriority bool `json:"minAvailableByPriority"`
+	// GangTimeoutSeconds is how long a group may stay under minAvailable
+	// before an event is recorded on its pending members. Zero disables
+	// the check.
+	GangTimeoutSeconds int64 `json:"gangTimeoutSeconds"`
+	// DeleteStuckGangs also deletes the pending members of such groups, so
+	// their controller recreates them instead of them holding queue slots.
+	DeleteStuckGangs bool `json:"deleteStuckGangs"`
+	// GangBackoffSeconds holds a group rejected for being under minAvailable
+	// back for this many seconds per missing member, PreFilter rejects its
+	// members without listing the group again until then. A new member
+	// ends the backoff early. Zero disables it.
+	GangBackoffSeconds int64 `json:"gangBackoffSeconds"`
+	// MaxGangBackoffSeconds caps the backoff, 60 when unset.
+	MaxGangBackoffSeconds int64 `json:"maxGangBackoffSeconds"`
+	// GroupNameNormalizer rewrites the group names reported in metric
+	// labels and events, e.g. collapsing the ids of train-job-<id> groups
+	// into train-job-*. Groups are still matched by their real names.
+	// Metrics count the groups its pattern doesn't match, or every group
+	// without it, under the "other" label.
+	GroupNameNormalizer *GroupNameNormalizer `json:"groupNameNormalizer"`
+	// GroupTopologyKey is the node label whose values the members of a
+	// group are spread evenly over: Filter rejects the nodes where the pod
+	// would leave a domain with more than GroupMaxSkew members over the
+	// least populated one, counting the members in the snapshot. Nodes
+	// without the label are rejected. Empty disables the spread.
+	GroupTopologyKey string `json:"groupTopologyKey"`
+	// GroupMaxSkew defaults to 1.
+	GroupMaxSkew int32 `json:"groupMaxSkew"`
+	// NormalizeMin and NormalizeMax bound the range NormalizeScore maps the
+	// raw scores to, within the framework's [0,100]. Leaving both zero
+	// keeps the framework's range.
+	NormalizeMin int64 `json:"normalizeMin"`
+	NormalizeMax int64 `json:"normalizeMax"`
 }
 
 type CustomScheduler struct {
-	handle    framework.Handle
-	scoreMode string
+	handle         framework.Handle
+	scoreMode      ScoreMode
+	scoreCurve     string
+	stableTiebreak bool
+	hardFailAfter  time.Duration
+	groups         *groupCounter
+	// clock is the time source of the group deadlines, nil means the wall
+	// clock. Tests inject a fake one to get reproducible results.
+	clock clock.PassiveClock
+
+	preferredNodeLabels       map[string]string
+	preferredNodeLabelsWeight int64
+	skipDaemonSetPods         bool
+	groupStabilization        time.Duration
+	groupBy                   []string
+	groupNameLabels           []string
+	scoreBy                   string
+	groupSelector             labels.Selector
+	preferredLabelKey         string
+	preferredLabelValue       string
+	preferredBonus            int64
+	// countPhases is nil when every phase counts.
+	countPhases map[v1.PodPhase]struct{}
+	// excludedNamespaces is nil when pods of every namespace are gated.
+	excludedNamespaces map[string]struct{}
+	skipUngrouped      bool
+	packRatio          float64
+	costLabel          string
+	costWeight         int64
+	// maxActiveGroups is zero when any number of groups can be active.
+	maxActiveGroups int
+	// activeGroupTTL is zero when active groups keep their slot until bound.
+	activeGroupTTL  time.Duration
+	minFreeMemory   int64
+	excludeLabel    string
+	groupByOwner    bool
+	namespaceModes  map[string]ScoreMode
+	scoringProfiles map[string]ScoringProfile
+	// unknownProfiles holds the unknown profile names already warned about.
+	unknownProfiles sync.Map
+	// rawModeLabels holds the raw quantity modes of scoreMode labels already
+	// warned about while NormalizeScores is off.
+	rawModeLabels   sync.Map
+	capacityCheck   bool
+	manageGangGates bool
+	// caseInsensitiveGroups lowercases group values before comparing them.
+	caseInsensitiveGroups bool
+	annotationKey         string
+	componentCaps         map[string]int64
+	scoreClamp            int64
+	// dominantPreference is empty when the DominantResource mode prefers the
+	// least utilized nodes.
+	dominantPreference ScoreMode
+	// defaultMinAvailable is zero when grouped pods need a minAvailable label.
+	defaultMinAvailable int
+	// gpuMemoryResource is empty for the default GPU memory resource.
+	gpuMemoryResource      v1.ResourceName
+	minAvailableAnnotation string
+	// coldNodeWindow is zero when recently ready nodes aren't penalized.
+	coldNodeWindow         time.Duration
+	coldNodePenaltyPercent int64
+	pressurePenaltyPercent int64
+	filterPressuredNodes   bool
+	priorityWeighted       bool
+	podCountAware          bool
+	softGang               bool
+	// gangGatePhase is empty when gangs are gated at PreFilter.
+	gangGatePhase string
+	// allocatableOverrideName is empty when nodes are scored by their real
+	// allocatable, allocatableOverrides is set once the ConfigMap is watched.
+	allocatableOverrideNamespace string
+	allocatableOverrideName      string
+	allocatableOverrides         corelisters.ConfigMapNamespaceLister
+	allocatableOverridesSynced   cache.InformerSynced
+	simulatePlacement            bool
+	// breaker is nil when lister errors never turn gang gating off.
+	breaker *listerBreaker
+	// backoff is nil when rejected groups are rechecked on every retry.
+	backoff *gangBackoff
+	// freeMemoryBasis is empty when the allocatable memory is scored.
+	freeMemoryBasis   string
+	groupZoneAffinity bool
+	// skipNormalize is set when NormalizeScores is off.
+	skipNormalize          bool
+	minAvailableByPriority bool
+	// gangTimeout is zero when stuck gangs aren't looked for.
+	gangTimeout      time.Duration
+	deleteStuckGangs bool
+	// stuckGangs are the groups already reported stuck, only the reaper
+	// goroutine accesses it.
+	stuckGangs map[string]struct{}
+	// groupNamePattern is nil when group names are reported as they are.
+	groupNamePattern     *regexp.Regexp
+	groupNameReplacement string
+	// groupTopologyKey is empty when groups aren't spread.
+	groupTopologyKey string
+	groupMaxSkew     int32
+	// scorer computes the raw scores of the configured mode, nil when
+	// Score computes them itself.
+	scorer Scorer
+	// skipGangGating is set when GangGating is off.
+	skipGangGating bool
+	// groupCounter is nil when groups are counted from the local pods.
+	groupCounter GroupCounter
+	// normalizeMin and normalizeMax are both zero for the framework's range.
+	normalizeMin int64
+	normalizeMax int64
+
+	// stopCh is closed by Close; background goroutines owned by the plugin
+	// must return once it is closed. It must not start informers the plugin
+	// doesn't own.
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	// handlers are the informer event handlers registered by the plugin.
+	handlers []handlerRegistration
+}
+
+// handlerRegistration pairs an informer with a handler registered on it.
+type handlerRegistration struct {
+	informer     cache.SharedIndexInformer
+	registration cache.ResourceEventHandlerRegistration
 }
 
 var _ framework.PreFilterPlugin = &CustomScheduler{}
 var _ framework.ScorePlugin = &CustomScheduler{}
+var _ io.Closer = &CustomScheduler{}
 
 // Name is the name of the plugin used in Registry and configurations.
 const (
 	Name              string = "CustomScheduler"
 	groupNameLabel    string = "podGroup"
 	minAvailableLabel string = "minAvailable"
-	leastMode         string = "Least"
-	mostMode          string = "Most"
+	scoreModeLabel    string = "scoreMode"
+	gangReadyReason   string = "GangReady"
+	linearCurve       string = "linear"
+	logCurve          string = "log"
+	stepCurve         string = "step"
+)
+
+// Roles split a group into parts with their own minimums, e.g. parameter
+// servers and workers of one job. The annotation holds a JSON object mapping
+// each podRole label value to its minimum.
+const (
+	roleLabel                    string = "podRole"
+	minAvailableByRoleAnnotation string = "minAvailableByRole"
+)
+
+// Memory figures the Least and Most modes can score by.
+const (
+	memoryScoreBy      string = "memory"
+	podCapacityScoreBy string = "podCapacity"
+)
+
+// Memory figures scoreBy memory can count as free.
+const (
+	allocatableBasis               string = "allocatable"
+	allocatableMinusRequestedBasis string = "allocatableMinusRequested"
+)
+
+// defaultGPUMemoryResource is the scalar resource fractional GPU setups
+// expose the GPU memory of a node as.
+const defaultGPUMemoryResource v1.ResourceName = "nvidia.com/gpu-memory"
+
+// Score components ComponentCaps can bound.
+const (
+	resourceComponent string = "resource"
+	costComponent     string = "cost"
+	affinityComponent string = "affinity"
 )
 
+// stepCurveBucket is the width of a tier under the step curve. Nodes whose
+// resource value falls into the same bucket receive the same score.
+const stepCurveBucket int64 = 1 << 30
+
+// tiebreakSlots is the number of low-order score slots reserved for the
+// node name hash when StableTiebreak is enabled.
+const tiebreakSlots int64 = 1 << 10
+
+// worstScore is the raw score of nodes that can't be scored, e.g. nodes that
+// don't report their allocatable resources yet or lack the scored annotation.
+// NormalizeScore maps it to the minimum score.
+const worstScore int64 = math.MinInt64
+
+// defaultCountPhases are the pod phases counted toward minAvailable unless
+// CountPhases is set.
+var defaultCountPhases = []string{string(v1.PodPending), string(v1.PodRunning)}
+
+// defaultActiveGroupTTL frees the slots of active groups when
+// ActiveGroupTTLSeconds isn't set.
+const defaultActiveGroupTTL = 5 * time.Minute
+
+// defaultExcludedNamespaces are the namespaces skipped by PreFilter unless
+// ExcludedNamespaces is set.
+var defaultExcludedNamespaces = []string{metav1.NamespaceSystem, metav1.NamespacePublic}
+
+// defaultPreferredNodeLabelsWeight is the affinity bonus percentage used when
+// PreferredNodeLabels is set without a weight.
+const defaultPreferredNodeLabelsWeight int64 = 10
+
+// defaultModeEnv is the environment variable DefaultMode is initialized from.
+const defaultModeEnv = "CUSTOM_SCHEDULER_DEFAULT_MODE"
+
+// DefaultMode is the mode used when the plugin args don't set one. The
+// precedence is: mode in the args, then DefaultMode, then Least. It is read
+// from the CUSTOM_SCHEDULER_DEFAULT_MODE environment variable at startup and
+// can be overridden before the scheduler builds its plugins.
+var DefaultMode = ScoreMode(os.Getenv(defaultModeEnv))
+
+// defaultMode returns DefaultMode, or Least when it is unset or invalid.
+func defaultMode() ScoreMode {
+	if DefaultMode == "" {
+		return leastMode
+	}
+	if !isValidMode(DefaultMode) {
+		log.Printf("Warning: invalid default mode %q, using mode %s.", DefaultMode, leastMode)
+		return leastMode
+	}
+	return DefaultMode
+}
+
 func (cs *CustomScheduler) Name() string {
 	return Name
 }
 
 // New initializes and returns a new CustomScheduler plugin.
 func New(obj runtime.Object, h framework.Handle) (framework.Plugin, error) {
-	cs := CustomScheduler{}
-	mode := leastMode
+	cs := CustomScheduler{
+		scoreMode:           defaultMode(),
+		scoreCurve:          linearCurve,
+		skipDaemonSetPods:   true,
+		scoreBy:             memoryScoreBy,
+		countPhases:         phaseSet(defaultCountPhases),
+		excludedNamespaces:  namespaceSet(defaultExcludedNamespaces),
+		skipUngrouped:       true,
+		defaultMinAvailable: 1,
+		activeGroupTTL:      defaultActiveGroupTTL,
+	}
 	if obj != nil {
 		args := obj.(*runtime.Unknown)
-		var csArgs CustomSchedulerArgs
+		csArgs := CustomSchedulerArgs{Mode: cs.scoreMode, SkipDaemonSetPods: true, SkipUngrouped: true, GangGating: true, DefaultMinAvailable: 1, NormalizeScores: true}
 		if err := json.Unmarshal(args.Raw, &csArgs); err != nil {
-			fmt.Printf("Error unmarshal: %v\n", err)
+			return nil, fmt.Errorf("error decoding args: %v", err)
+		}
+		csArgs.Mode = canonicalMode(csArgs.Mode)
+		for ns, mode := range csArgs.NamespaceModes {
+			csArgs.NamespaceModes[ns] = canonicalMode(mode)
+		}
+		for name, profile := range csArgs.ScoringProfiles {
+			if profile.Mode != "" {
+				profile.Mode = canonicalMode(profile.Mode)
+				csArgs.ScoringProfiles[name] = profile
+			}
+		}
+		if err := csArgs.Validate(); err != nil {
+			return nil, err
+		}
+		cs.scoreMode = csArgs.Mode
+		if csArgs.ScoreCurve != "" {
+			cs.scoreCurve = csArgs.ScoreCurve
+		}
+		cs.stableTiebreak = csArgs.StableTiebreak
+		cs.hardFailAfter = time.Duration(csArgs.HardFailAfterSeconds) * time.Second
+		cs.preferredNodeLabels = csArgs.PreferredNodeLabels
+		cs.preferredNodeLabelsWeight = csArgs.PreferredNodeLabelsWeight
+		if len(cs.preferredNodeLabels) > 0 && cs.preferredNodeLabelsWeight == 0 {
+			cs.preferredNodeLabelsWeight = defaultPreferredNodeLabelsWeight
 		}
-		mode = csArgs.Mode
-		if mode != leastMode && mode != mostMode {
-			return nil, fmt.Errorf("invalid mode, got %s", mode)
+		cs.skipDaemonSetPods = csArgs.SkipDaemonSetPods
+		cs.groupStabilization = time.Duration(csArgs.GroupStabilizationSeconds) * time.Second
+		cs.groupBy = csArgs.GroupBy
+		cs.groupNameLabels = splitLabelKeys(csArgs.GroupNameLabels)
+		if csArgs.ScoreBy != "" {
+			cs.scoreBy = csArgs.ScoreBy
 		}
+		if csArgs.GroupSelectorExpressions != nil {
+			// already checked by Validate
+			cs.groupSelector, _ = metav1.LabelSelectorAsSelector(csArgs.GroupSelectorExpressions)
+		}
+		if csArgs.PreferredNodeLabel != "" {
+			cs.preferredLabelKey, cs.preferredLabelValue, _ = parsePreferredNodeLabel(csArgs.PreferredNodeLabel)
+			cs.preferredBonus = csArgs.PreferredBonus
+		}
+		if csArgs.CountPhases != nil {
+			cs.countPhases = phaseSet(csArgs.CountPhases)
+		}
+		if csArgs.ExcludedNamespaces != nil {
+			cs.excludedNamespaces = namespaceSet(csArgs.ExcludedNamespaces)
+		}
+		cs.skipUngrouped = csArgs.SkipUngrouped
+		cs.packRatio = csArgs.PackRatio
+		cs.costLabel = csArgs.CostLabel
+		cs.costWeight = csArgs.CostWeight
+		cs.maxActiveGroups = int(csArgs.MaxActiveGroups)
+		if csArgs.ActiveGroupTTLSeconds > 0 {
+			cs.activeGroupTTL = time.Duration(csArgs.ActiveGroupTTLSeconds) * time.Second
+		}
+		if csArgs.MinFreeMemoryBytes != "" {
+			// already checked by Validate
+			q, _ := resource.ParseQuantity(csArgs.MinFreeMemoryBytes)
+			cs.minFreeMemory = q.Value()
+		}
+		cs.excludeLabel = csArgs.GroupExcludeLabel
+		cs.groupByOwner = csArgs.GroupByOwner
+		cs.namespaceModes = csArgs.NamespaceModes
+		cs.scoringProfiles = csArgs.ScoringProfiles
+		cs.capacityCheck = csArgs.CapacityCheck
+		cs.manageGangGates = csArgs.ManageGangGates
+		cs.caseInsensitiveGroups = csArgs.CaseInsensitiveGroups
+		cs.annotationKey = csArgs.AnnotationKey
+		cs.componentCaps = csArgs.ComponentCaps
+		cs.scoreClamp = csArgs.ScoreClamp
+		if csArgs.MaxRawScore > 0 {
+			cs.scoreClamp = csArgs.MaxRawScore
+		}
+		cs.skipGangGating = !csArgs.GangGating
+		cs.dominantPreference = csArgs.DominantPreference
+		cs.defaultMinAvailable = csArgs.DefaultMinAvailable
+		cs.gpuMemoryResource = v1.ResourceName(csArgs.GPUMemoryResource)
+		cs.minAvailableAnnotation = csArgs.MinAvailableAnnotation
+		cs.coldNodeWindow = time.Duration(csArgs.ColdNodeWindowSeconds) * time.Second
+		cs.coldNodePenaltyPercent = csArgs.ColdNodePenaltyPercent
+		cs.pressurePenaltyPercent = csArgs.PressurePenaltyPercent
+		cs.filterPressuredNodes = csArgs.FilterPressuredNodes
+		cs.priorityWeighted = csArgs.PriorityWeighted
+		cs.podCountAware = csArgs.PodCountAware
+		cs.softGang = csArgs.SoftGang
+		cs.gangGatePhase = csArgs.GangGatePhase
+		cs.allocatableOverrideNamespace = csArgs.AllocatableOverrideNamespace
+		cs.allocatableOverrideName = csArgs.AllocatableOverrideConfigMap
+		cs.simulatePlacement = csArgs.SimulatePlacement
+		cs.freeMemoryBasis = csArgs.FreeMemoryBasis
+		cs.groupZoneAffinity = csArgs.GroupZoneAffinity
+		cs.skipNormalize = !csArgs.NormalizeScores
+		cs.minAvailableByPriority = csArgs.MinAvailableByPriority
+		cs.gangTimeout = time.Duration(csArgs.GangTimeoutSeconds) * time.Second
+		cs.deleteStuckGangs = csArgs.DeleteStuckGangs
+		cs.groupTopologyKey = csArgs.GroupTopologyKey
+		cs.groupMaxSkew = csArgs.GroupMaxSkew
+		if csArgs.GroupNameNormalizer != nil {
+			// already checked by Validate
+			cs.groupNamePattern, _ = regexp.Compile(csArgs.GroupNameNormalizer.Pattern)
+			cs.groupNameReplacement = csArgs.GroupNameNormalizer.Replacement
+		}
+		cs.breaker = newListerBreaker(csArgs.ListerBreakerThreshold, time.Duration(csArgs.ListerBreakerWindowSeconds)*time.Second)
+		cs.backoff = newGangBackoff(time.Duration(csArgs.GangBackoffSeconds)*time.Second, time.Duration(csArgs.MaxGangBackoffSeconds)*time.Second)
+		cs.normalizeMin = csArgs.NormalizeMin
+		cs.normalizeMax = csArgs.NormalizeMax
 	}
+	cs.scorer = cs.scorerFor(cs.scoreMode)
+	registerMetrics()
 	cs.handle = h
-	cs.scoreMode = mode
-	log.Printf("Custom scheduler runs with the mode: %s.", mode)
+	cs.clock = clock.RealClock{}
+	cs.stopCh = make(chan struct{})
+	cs.groups = newGroupCounter(cs.groupName)
+	if h != nil && cs.allocatableOverrideName != "" {
+		if err := cs.startAllocatableOverrides(h); err != nil {
+			return nil, err
+		}
+	}
+	if h != nil && h.SharedInformerFactory() != nil {
+		informer := h.SharedInformerFactory().Core().V1().Pods().Informer()
+		reg, err := informer.AddEventHandler(cs.groups.eventHandler())
+		if err != nil {
+			return nil, fmt.Errorf("error registering group counter: %v", err)
+		}
+		cs.handlers = append(cs.handlers, handlerRegistration{informer: informer, registration: reg})
+		if cs.backoff != nil {
+			reg, err := informer.AddEventHandler(cs.gangBackoffHandler())
+			if err != nil {
+				return nil, fmt.Errorf("error registering gang backoff handler: %v", err)
+			}
+			cs.handlers = append(cs.handlers, handlerRegistration{informer: informer, registration: reg})
+		}
+		if cs.manageGangGates {
+			if err := cs.startGangGates(informer); err != nil {
+				return nil, err
+			}
+		}
+
+		// the shared factory belongs to the scheduler, which starts it once
+		// every plugin is built; HasSynced reports when the handlers caught up
+		if cs.gangTimeout > 0 {
+			cs.startGangReaper()
+		}
+	}
+	log.Printf("Custom scheduler runs with the mode: %s, curve: %s.", cs.scoreMode, cs.scoreCurve)
 
 	return &cs, nil
 }
 
+// Close stops the background goroutines owned by the plugin, the gang reaper
+// and gate releaser, and detaches its informer handlers. The shared informer
+// factory belongs to the scheduler and keeps running. The framework of this
+// Kubernetes version never closes plugins, so only tests call Close. It is
+// safe to call more than once.
+func (cs *CustomScheduler) Close() error {
+	var err error
+	cs.closeOnce.Do(func() {
+		if cs.stopCh != nil {
+			close(cs.stopCh)
+		}
+		for _, h := range cs.handlers {
+			if rerr := h.informer.RemoveEventHandler(h.registration); rerr != nil && err == nil {
+				err = rerr
+			}
+		}
+		cs.handlers = nil
+	})
+	return err
+}
+
+// HasSynced reports whether the informers the plugin reads from have synced
+// and its handlers have processed their initial list, so it is safe to
+// declare the scheduler ready. Informers added later must be checked here
+// too.
+func (cs *CustomScheduler) HasSynced() bool {
+	if cs.handle == nil || cs.handle.SharedInformerFactory() == nil {
+		return false
+	}
+	if !cs.handle.SharedInformerFactory().Core().V1().Pods().Informer().HasSynced() {
+		return false
+	}
+	for _, h := range cs.handlers {
+		if !h.registration.HasSynced() {
+			return false
+		}
+	}
+	if cs.allocatableOverridesSynced != nil && !cs.allocatableOverridesSynced() {
+		return false
+	}
+	return true
+}
+
 // filter the pod if the pod in group is less than minAvailable
 func (cs *CustomScheduler) PreFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod) (*framework.PreFilterResult, *framework.Status) {
 	log.Printf("Pod %s is in Prefilter phase.", pod.Name)
@@ -70,32 +715,545 @@ func (cs *CustomScheduler) PreFilter(ctx context.Context, state *framework.Cycle
 	// 2. retrieve the pod with the same group label
 	// 3. justify if the pod can be scheduled
 
-	// Extract the label of the pod
-	groupLabel, exists := pod.ObjectMeta.Labels["podGroup"]
-	if !exists {
-		return nil, framework.AsStatus(fmt.Errorf("group label not found on pod %s", pod.Name))
+	// DaemonSet and static pods are placed per node, never as a gang
+	if cs.skipDaemonSetPods && isDaemonSetOrMirrorPod(pod) {
+		return nil, newStatus
+	}
+	// system namespaces are never gang gated, even if labeled by mistake.
+	// Not Skip: the framework would skip this plugin's Filter too, and its
+	// node predicates apply to every pod
+	if cs.excluded(pod.Namespace) {
+		return nil, newStatus
+	}
+
+	// the pods can't be listed before the informers are wired up, retry
+	// rather than crash during warm-up
+	if cs.handle.SharedInformerFactory() == nil {
+		log.Printf("Warning: informer factory unavailable, can't list the group of pod %s yet.", pod.Name)
+		return nil, framework.NewStatus(framework.Unschedulable, "informer factory unavailable, group members can't be listed yet")
+	}
+
+	// the lister kept failing, schedule pods one by one until it's retried
+	if cs.breaker.isOpen(cs.now()) {
+		log.Printf("Warning: lister breaker open, pod %s passes without gang gating.", pod.Name)
+		return nil, newStatus
 	}
 
-	// Create a selector from the pod labels
-	selector := labels.SelectorFromSet(labels.Set{"podGroup": groupLabel})
+	// the group was short a moment ago and no member joined since
+	if group, ok := cs.groupName(pod); ok && !cs.skipGangGating {
+		if message, held := cs.backoff.heldMessage(group, cs.now()); held {
+			return nil, framework.NewStatus(framework.Unschedulable, message)
+		}
+	}
 
-	// Use the lister to fetch pods
-	pods, err := cs.handle.SharedInformerFactory().Core().V1().Pods().Lister().List(selector)
+	// Extract the group of the pod and fetch its members
+	groupLabel, pods, exists, err := cs.groupMembers(pod)
 	if err != nil {
-		return nil, framework.AsStatus(fmt.Errorf("error listing pods with selector %v: %v", selector, err))
+		return nil, cs.listerFailed(err)
+	}
+	if !exists {
+		// the pod isn't part of any gang, let it through rather than fail
+		// it, with Success so Filter still runs for it
+		if cs.skipUngrouped {
+			return nil, newStatus
+		}
+		if cs.groupByOwner {
+			return nil, framework.AsStatus(fmt.Errorf("%w on pod %s", ErrGroupOwnerMissing, pod.Name))
+		}
+		return nil, framework.AsStatus(fmt.Errorf("%w on pod %s", ErrGroupLabelMissing, pod.Name))
+	}
+	// no retry helps a member larger than every node
+	if status := cs.checkPodMemoryFits(pod); status != nil {
+		return nil, status
+	}
+	if err := cs.recordGroupSpread(state, pod, groupLabel); err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	// the group only matters for scoring, let its pods go one by one
+	if cs.skipGangGating {
+		return nil, newStatus
 	}
 
-	minAvailable, err := strconv.Atoi(pod.ObjectMeta.Labels["minAvailable"])
+	minAvailable, byRole, err := cs.gangMinimum(pod)
 	if err != nil {
-		return nil, framework.AsStatus(fmt.Errorf("group minAvail not found on pod %s", pod.Name))
+		return nil, framework.AsStatus(err)
+	}
+	count, err := cs.groupCount(pod, groupLabel, pods)
+	if err != nil {
+		return nil, cs.listerFailed(err)
+	}
+	cs.breaker.success()
+	role, roleCount, roleMin, roleShort := cs.shortRole(pods, byRole)
+	oldest := oldestCreation(pod, pods)
+	// give up on gangs that stayed incomplete past the deadline
+	expired := cs.hardFailAfter > 0 && !oldest.IsZero() && cs.now().Sub(oldest) > cs.hardFailAfter
+	cs.trackGangReady(pod, groupLabel, count >= minAvailable && !roleShort)
+	cs.observeGangSize(groupLabel, count, minAvailable)
+	// let the gang make progress, NormalizeScore scores it down instead; a
+	// short role scores it down as much as a short group
+	if cs.softGang {
+		observed, required := count, minAvailable
+		if roleShort && roleCount*required < observed*roleMin {
+			observed, required = roleCount, roleMin
+		}
+		if observed < required {
+			recordGangShortfall(state, observed, required)
+		}
+		return nil, newStatus
+	}
+	// Permit holds the members until enough of them are placed instead
+	if cs.gangGatePhase == permitGatePhase {
+		return nil, newStatus
 	}
-	if len(pods) < minAvailable {
-		return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Not enough pods in group %s, minimum required is %d", groupLabel, minAvailable))
+	if count < minAvailable {
+		if expired {
+			return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("Not enough pods in group %s after %v, %d present, minimum required is %d", groupLabel, cs.hardFailAfter, count, minAvailable))
+		}
+		// the message carries the current count, so each rejection reflects
+		// how far the group is from its minimum; the retries themselves are
+		// driven by the events from EventsToRegister
+		message := fmt.Sprintf("Not enough pods in group %s, %d present, minimum required is %d", groupLabel, count, minAvailable)
+		return nil, framework.NewStatus(framework.Unschedulable, cs.backoff.hold(groupLabel, message, minAvailable-count, cs.now()))
+	}
+	if roleShort {
+		if expired {
+			return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("Not enough pods with role %s in group %s after %v, %d present, minimum required is %d", role, groupLabel, cs.hardFailAfter, roleCount, roleMin))
+		}
+		return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Not enough pods with role %s in group %s, %d present, minimum required is %d", role, groupLabel, roleCount, roleMin))
+	}
+
+	// give the controller time to create the rest of the group
+	if cs.groupStabilization > 0 && !oldest.IsZero() {
+		if age := cs.now().Sub(oldest); age < cs.groupStabilization {
+			return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Group %s is %v old, waiting %v for all members to appear", groupLabel, age.Round(time.Second), cs.groupStabilization))
+		}
+	}
+
+	// fail fast when the gang can't possibly fit
+	if cs.capacityCheck {
+		if status := cs.checkGangCapacity(pod, groupLabel, pods, minAvailable); status != nil {
+			return nil, status
+		}
+	}
+
+	// bound how many gangs are in flight at once, the slot frees up when
+	// every member of an active group is bound or its ttl passes
+	if cs.maxActiveGroups > 0 && cs.groups != nil && !cs.groups.activate(groupLabel, cs.maxActiveGroups, cs.now(), cs.activeGroupTTL) {
+		return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Group %s waits for one of %d active groups to finish binding", groupLabel, cs.maxActiveGroups))
 	}
 
 	return nil, newStatus
 }
 
+// listerFailed records a failure to list or count group members with the
+// breaker and returns the pod's status.
+func (cs *CustomScheduler) listerFailed(err error) *framework.Status {
+	if cs.breaker.failure(cs.now()) {
+		log.Printf("Warning: %d consecutive lister errors, gang gating is off for %v: %v", cs.breaker.threshold, cs.breaker.window, err)
+		listerBreakerTripsTotal.Inc()
+	}
+	return framework.AsStatus(err)
+}
+
+// gangMinimum returns how many members the gang of the pod needs, along with
+// the per-role minimums if any are set.
+func (cs *CustomScheduler) gangMinimum(pod *v1.Pod) (int, map[string]int, error) {
+	byRole, err := minAvailableByRoleOf(pod)
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, explicit := cs.rawMinAvailable(pod); !explicit && byRole != nil {
+		// the role minimums alone define the gang
+		var minAvailable int
+		for _, m := range byRole {
+			minAvailable += m
+		}
+		return minAvailable, byRole, nil
+	}
+	minAvailable, err := cs.minAvailableOf(pod)
+	if err != nil {
+		return 0, nil, err
+	}
+	return minAvailable, byRole, nil
+}
+
+// now returns the current time of the plugin's clock.
+func (cs *CustomScheduler) now() time.Time {
+	if cs.clock == nil {
+		return time.Now()
+	}
+	return cs.clock.Now()
+}
+
+// groupMembers returns the name of the pod's group and the pods listed as
+// its members, ok is false when the pod isn't part of a group.
+func (cs *CustomScheduler) groupMembers(pod *v1.Pod) (string, []*v1.Pod, bool, error) {
+	if cs.groupByOwner {
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil {
+			return "", nil, false, nil
+		}
+		// owners are namespaced, so are their pods
+		all, err := cs.handle.SharedInformerFactory().Core().V1().Pods().Lister().Pods(pod.Namespace).List(labels.Everything())
+		if err != nil {
+			return "", nil, false, fmt.Errorf("error listing pods in namespace %s: %v", pod.Namespace, err)
+		}
+		var pods []*v1.Pod
+		for _, p := range all {
+			if ref := metav1.GetControllerOf(p); ref != nil && ref.UID == owner.UID && cs.matchesGroupSelector(p) {
+				pods = append(pods, p)
+			}
+		}
+		return ownerGroupName(pod.Namespace, owner), pods, true, nil
+	}
+
+	groupSet, ok := cs.groupLabels(pod)
+	if !ok {
+		return "", nil, false, nil
+	}
+	// a selector can't match either of the group name labels, list each
+	var candidates []*v1.Pod
+	listed := map[string]bool{}
+	for _, keys := range cs.groupSelectorKeys() {
+		selector := cs.groupSelectorFor(keys)
+		pods, err := cs.handle.SharedInformerFactory().Core().V1().Pods().Lister().List(selector)
+		if err != nil {
+			return "", nil, false, fmt.Errorf("error listing pods with selector %v: %v", selector, err)
+		}
+		for _, p := range pods {
+			key := p.Namespace + "/" + p.Name
+			if !listed[key] {
+				listed[key] = true
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	// compare the normalized values, a selector only matches them verbatim
+	var pods []*v1.Pod
+	for _, p := range candidates {
+		if set, ok := cs.groupLabels(p); ok && labels.Equals(set, groupSet) {
+			pods = append(pods, p)
+		}
+	}
+	return groupNameOf(groupSet), pods, true, nil
+}
+
+// ownerGroupName names a group of pods sharing a controller.
+func ownerGroupName(namespace string, owner *metav1.OwnerReference) string {
+	return fmt.Sprintf("%s/%s/%s", namespace, owner.Kind, owner.Name)
+}
+
+// matchesGroupSelector reports whether the pod is within the group selector
+// expressions, if any.
+func (cs *CustomScheduler) matchesGroupSelector(pod *v1.Pod) bool {
+	return cs.groupSelector == nil || cs.groupSelector.Matches(labels.Set(pod.Labels))
+}
+
+// groupKeys returns the label keys that define a group.
+func (cs *CustomScheduler) groupKeys() []string {
+	if len(cs.groupBy) == 0 {
+		return []string{groupNameLabel}
+	}
+	return cs.groupBy
+}
+
+// groupLabels returns the pod's normalized values for every group key. ok is
+// false when the pod lacks any of them. With group name labels, the value of
+// the first one the pod carries is keyed by the first configured label.
+func (cs *CustomScheduler) groupLabels(pod *v1.Pod) (labels.Set, bool) {
+	if len(cs.groupNameLabels) > 0 {
+		for _, key := range cs.groupNameLabels {
+			if value, ok := pod.Labels[key]; ok {
+				return labels.Set{cs.groupNameLabels[0]: cs.normalizeGroupValue(value)}, true
+			}
+		}
+		return nil, false
+	}
+	set := labels.Set{}
+	for _, key := range cs.groupKeys() {
+		value, ok := pod.Labels[key]
+		if !ok {
+			return nil, false
+		}
+		set[key] = cs.normalizeGroupValue(value)
+	}
+	return set, true
+}
+
+// normalizeGroupValue trims a group label value, and lowercases it when
+// groups are case-insensitive, so values copied with stray spaces or another
+// casing don't split a group.
+func (cs *CustomScheduler) normalizeGroupValue(value string) string {
+	value = strings.TrimSpace(value)
+	if cs.caseInsensitiveGroups {
+		value = strings.ToLower(value)
+	}
+	return value
+}
+
+// groupSelectorKeys returns the sets of keys group members may carry, a
+// set per group name label or else just the group keys.
+func (cs *CustomScheduler) groupSelectorKeys() [][]string {
+	if len(cs.groupNameLabels) == 0 {
+		return [][]string{cs.groupKeys()}
+	}
+	sets := make([][]string, 0, len(cs.groupNameLabels))
+	for _, key := range cs.groupNameLabels {
+		sets = append(sets, []string{key})
+	}
+	return sets
+}
+
+// groupSelectorFor selects the pods carrying every one of the keys, scoped
+// by the configured group selector expressions. The group values are compared
+// after normalization, by the caller.
+func (cs *CustomScheduler) groupSelectorFor(keys []string) labels.Selector {
+	selector := labels.NewSelector()
+	for _, key := range keys {
+		req, err := labels.NewRequirement(key, selection.Exists, nil)
+		if err != nil {
+			// keys are checked by Validate
+			continue
+		}
+		selector = selector.Add(*req)
+	}
+	if cs.groupSelector != nil {
+		reqs, _ := cs.groupSelector.Requirements()
+		selector = selector.Add(reqs...)
+	}
+	return selector
+}
+
+// splitLabelKeys flattens comma-separated entries into single label keys,
+// dropping empty ones.
+func splitLabelKeys(entries []string) []string {
+	var keys []string
+	for _, entry := range entries {
+		for _, key := range strings.Split(entry, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// groupName returns the name of the group the pod counts toward, ok is false
+// for ungrouped pods, excluded pods and pods outside the group selector
+// expressions.
+func (cs *CustomScheduler) groupName(pod *v1.Pod) (string, bool) {
+	if cs.isExcluded(pod) || !cs.matchesGroupSelector(pod) {
+		return "", false
+	}
+	if cs.groupByOwner {
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil {
+			return "", false
+		}
+		return ownerGroupName(pod.Namespace, owner), true
+	}
+	set, ok := cs.groupLabels(pod)
+	if !ok {
+		return "", false
+	}
+	return groupNameOf(set), true
+}
+
+// groupNameOf names a group by its label value, or by the sorted key=value
+// pairs when the group is defined by several labels.
+func groupNameOf(set labels.Set) string {
+	if len(set) == 1 {
+		for _, v := range set {
+			return v
+		}
+	}
+	return set.String()
+}
+
+// isDaemonSetOrMirrorPod reports whether the pod is owned by a DaemonSet or
+// is the mirror of a static pod.
+func isDaemonSetOrMirrorPod(pod *v1.Pod) bool {
+	if _, ok := pod.Annotations[v1.MirrorPodAnnotationKey]; ok {
+		return true
+	}
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+// minAvailableOf parses the minAvailable label of the pod, or else its
+// minAvailable annotation, ignoring surrounding whitespace. Pods without
+// either get the default minimum, unless the minimum must be explicit.
+func (cs *CustomScheduler) minAvailableOf(pod *v1.Pod) (int, error) {
+	raw, ok := cs.rawMinAvailable(pod)
+	if !ok {
+		if cs.defaultMinAvailable > 0 {
+			return cs.defaultMinAvailable, nil
+		}
+		return 0, fmt.Errorf("%w on pod %s", ErrMinAvailableMissing, pod.Name)
+	}
+	minAvailable, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || minAvailable <= 0 {
+		return 0, fmt.Errorf("%w, got %q on pod %s", ErrInvalidMinAvailable, raw, pod.Name)
+	}
+	return minAvailable, nil
+}
+
+// rawMinAvailable returns the unparsed minimum of the pod, the label taking
+// precedence over the annotation.
+func (cs *CustomScheduler) rawMinAvailable(pod *v1.Pod) (string, bool) {
+	if raw, ok := pod.Labels[minAvailableLabel]; ok {
+		return raw, true
+	}
+	if cs.minAvailableAnnotation == "" {
+		return "", false
+	}
+	raw, ok := pod.Annotations[cs.minAvailableAnnotation]
+	return raw, ok
+}
+
+// minAvailableByRoleOf parses the per-role minimums of the pod. It returns
+// nil when the pod has no minAvailableByRole annotation.
+func minAvailableByRoleOf(pod *v1.Pod) (map[string]int, error) {
+	raw, ok := pod.Annotations[minAvailableByRoleAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	byRole := map[string]int{}
+	if err := json.Unmarshal([]byte(raw), &byRole); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation on pod %s: %v", minAvailableByRoleAnnotation, pod.Name, err)
+	}
+	for role, m := range byRole {
+		if m < 0 {
+			return nil, fmt.Errorf("invalid %s annotation on pod %s: negative minimum for role %s", minAvailableByRoleAnnotation, pod.Name, role)
+		}
+	}
+	return byRole, nil
+}
+
+// shortRole reports the first role, in name order, whose counted members
+// fall short of its minimum.
+func (cs *CustomScheduler) shortRole(pods []*v1.Pod, byRole map[string]int) (role string, count, minimum int, short bool) {
+	return roleShortfall(pods, byRole, cs.countMembers)
+}
+
+// roleShortfall reports the first role, in name order, whose pods, as counted
+// by count, fall short of its minimum.
+func roleShortfall(pods []*v1.Pod, byRole map[string]int, count func([]*v1.Pod) int) (role string, counted, minimum int, short bool) {
+	roles := make([]string, 0, len(byRole))
+	for r := range byRole {
+		roles = append(roles, r)
+	}
+	sort.Strings(roles)
+
+	members := map[string][]*v1.Pod{}
+	for _, p := range pods {
+		if r, ok := p.Labels[roleLabel]; ok {
+			members[r] = append(members[r], p)
+		}
+	}
+	for _, r := range roles {
+		if c := count(members[r]); c < byRole[r] {
+			return r, c, byRole[r], true
+		}
+	}
+	return "", 0, 0, false
+}
+
+// trackGangReady records whether the group meets minAvailable and emits a
+// GangReady event on the pod that made it ready.
+func (cs *CustomScheduler) trackGangReady(pod *v1.Pod, group string, ready bool) {
+	if cs.groups == nil || !cs.groups.setReady(group, ready) {
+		return
+	}
+	if recorder := cs.handle.EventRecorder(); recorder != nil {
+		recorder.Eventf(pod, nil, v1.EventTypeNormal, gangReadyReason, "Scheduling", "Group %s reached its minimum of available pods", cs.reportedGroupName(group))
+	}
+}
+
+// countMembers returns how many of the listed group pods count toward
+// minAvailable.
+func (cs *CustomScheduler) countMembers(pods []*v1.Pod) int {
+	count := 0
+	for _, p := range pods {
+		// Gated pods can't be scheduled yet, so they don't count toward the group.
+		if len(p.Spec.SchedulingGates) > 0 {
+			continue
+		}
+		if cs.isExcluded(p) {
+			continue
+		}
+		if cs.countPhases != nil {
+			if _, ok := cs.countPhases[p.Status.Phase]; !ok {
+				continue
+			}
+		}
+		count++
+	}
+	return count
+}
+
+// podPriority returns the priority of the pod, 0 when it has none.
+func podPriority(pod *v1.Pod) int32 {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+// isExcluded reports whether the pod carries the group exclude label.
+func (cs *CustomScheduler) isExcluded(pod *v1.Pod) bool {
+	if cs.excludeLabel == "" {
+		return false
+	}
+	_, ok := pod.Labels[cs.excludeLabel]
+	return ok
+}
+
+// excluded reports whether pods of the namespace are never gang gated.
+func (cs *CustomScheduler) excluded(namespace string) bool {
+	_, ok := cs.excludedNamespaces[namespace]
+	return ok
+}
+
+// namespaceSet converts namespace names into a set, nil when there are
+// none.
+func namespaceSet(namespaces []string) map[string]struct{} {
+	if len(namespaces) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(namespaces))
+	for _, ns := range namespaces {
+		set[ns] = struct{}{}
+	}
+	return set
+}
+
+// phaseSet converts phase names into a set.
+func phaseSet(phases []string) map[v1.PodPhase]struct{} {
+	set := make(map[v1.PodPhase]struct{}, len(phases))
+	for _, p := range phases {
+		set[v1.PodPhase(p)] = struct{}{}
+	}
+	return set
+}
+
+// oldestCreation returns the creation time of the oldest pod in the group,
+// or the zero time if none of them has a creation timestamp.
+func oldestCreation(pod *v1.Pod, pods []*v1.Pod) time.Time {
+	oldest := pod.CreationTimestamp.Time
+	for _, p := range pods {
+		created := p.CreationTimestamp.Time
+		if !created.IsZero() && (oldest.IsZero() || created.Before(oldest)) {
+			oldest = created
+		}
+	}
+	return oldest
+}
+
 // PreFilterExtensions returns a PreFilterExtensions interface if the plugin implements one.
 func (cs *CustomScheduler) PreFilterExtensions() framework.PreFilterExtensions {
 	return nil
@@ -109,19 +1267,518 @@ func (cs *CustomScheduler) Score(ctx context.Context, state *framework.CycleStat
 	// 1. retrieve the node allocatable memory
 	// 2. return the score based on the scheduler mode
 
-	nodeinfo, err := cs.handle.SnapshotSharedLister().NodeInfos().Get(nodeName)
+	mode := cs.modeFor(pod)
+	// leave the decision to the other score plugins
+	if mode == neutralMode {
+		return 0, nil
+	}
+
+	// the snapshot may not be wired up yet while the scheduler warms up
+	lister := cs.handle.SnapshotSharedLister()
+	if lister == nil {
+		log.Printf("Warning: snapshot lister unavailable, giving node %s a neutral score.", nodeName)
+		return 0, nil
+	}
+	nodeinfo, err := lister.NodeInfos().Get(nodeName)
 	if err != nil {
 		return 0, framework.AsStatus(fmt.Errorf("nodeInfo not found on node %s", nodeName))
 	}
+	// a node that just registered may not report its resources yet
+	if nodeinfo.Allocatable == nil || nodeinfo.Requested == nil {
+		log.Printf("Warning: node %s has no allocatable resources yet, giving it the minimum score.", nodeName)
+		recordExplanation(state, nodeName, []string{"mode " + mode.String(), "allocatable unknown"})
+		return cs.unscorableScore(), nil
+	}
+	// simulate a bigger node for testing, the snapshot itself is left as is
+	override, overridden := cs.allocatableOverride(nodeName)
+	if overridden {
+		nodeinfo = nodeinfo.Clone()
+		nodeinfo.Allocatable.Memory = override
+	}
+
+	// nodes without a usable annotation lose to every annotated node
+	var annotationValue int64
+	if mode == leastAnnotationMode || mode == mostAnnotationMode {
+		value, ok := cs.nodeAnnotationValue(nodeinfo.Node())
+		if !ok {
+			recordExplanation(state, nodeName, []string{"mode " + mode.String(), "annotation " + cs.annotationKey + " missing"})
+			return cs.unscorableScore(), nil
+		}
+		annotationValue = value
+	}
+	// as do nodes without GPU memory against nodes with some, and nodes
+	// the pod's request of it doesn't fit on
+	var gpuMemory int64
+	if mode == leastGPUMemoryMode || mode == mostGPUMemoryMode {
+		resourceName := cs.gpuMemoryResourceName()
+		value, ok := nodeinfo.Allocatable.ScalarResources[resourceName]
+		if !ok {
+			recordExplanation(state, nodeName, []string{"mode " + mode.String(), string(resourceName) + " missing"})
+			return cs.unscorableScore(), nil
+		}
+		request := podScalarRequest(pod, resourceName)
+		gpuMemory = value - nodeinfo.Requested.ScalarResources[resourceName] - request
+		if gpuMemory < 0 {
+			recordExplanation(state, nodeName, []string{"mode " + mode.String(), fmt.Sprintf("%s request %d doesn't fit", resourceName, request)})
+			return cs.unscorableScore(), nil
+		}
+	}
+
+	var score int64
+	notes := []string{"mode " + mode.String()}
+	switch mode {
+	case leastAnnotationMode:
+		score = -annotationValue
+		notes = append(notes, fmt.Sprintf("annotation %s %d", cs.annotationKey, annotationValue))
+	case mostAnnotationMode:
+		score = annotationValue
+		notes = append(notes, fmt.Sprintf("annotation %s %d", cs.annotationKey, annotationValue))
+	case leastGPUMemoryMode:
+		score = -applyCurve(cs.scoreCurve, gpuMemory)
+		notes = append(notes, fmt.Sprintf("%s %d", cs.gpuMemoryResourceName(), gpuMemory))
+	case mostGPUMemoryMode:
+		score = applyCurve(cs.scoreCurve, gpuMemory)
+		notes = append(notes, fmt.Sprintf("%s %d", cs.gpuMemoryResourceName(), gpuMemory))
+	case leastMode, mostMode:
+		if score, err = cs.scorerFor(mode).Score(nodeinfo, pod); err != nil {
+			return 0, framework.AsStatus(fmt.Errorf("error scoring node %s: %v", nodeName, err))
+		}
+		notes = append(notes, fmt.Sprintf("%s %d", cs.scoreByFor(pod), cs.memoryValue(nodeinfo, pod)))
+	case blendMode:
+		// mix of Most weighted by packRatio and Least weighted by the rest
+		value := cs.memoryValue(nodeinfo, pod)
+		score = int64((2*cs.packRatio - 1) * float64(applyCurve(cs.scoreCurve, value)))
+		notes = append(notes, fmt.Sprintf("%s %d", cs.scoreByFor(pod), value), fmt.Sprintf("packRatio %v", cs.packRatio))
+	case leastEphemeralStorageMode:
+		// nodes that don't report ephemeral-storage have zero allocatable
+		score = -applyCurve(cs.scoreCurve, nodeinfo.Allocatable.EphemeralStorage)
+		notes = append(notes, fmt.Sprintf("ephemeral-storage %d", nodeinfo.Allocatable.EphemeralStorage))
+	case mostEphemeralStorageMode:
+		score = applyCurve(cs.scoreCurve, nodeinfo.Allocatable.EphemeralStorage)
+		notes = append(notes, fmt.Sprintf("ephemeral-storage %d", nodeinfo.Allocatable.EphemeralStorage))
+	case leastPodHeadroomMode:
+		headroom := podHeadroom(nodeinfo)
+		score = -headroom
+		notes = append(notes, fmt.Sprintf("pod headroom %d", headroom))
+	case mostPodHeadroomMode:
+		headroom := podHeadroom(nodeinfo)
+		score = headroom
+		notes = append(notes, fmt.Sprintf("pod headroom %d", headroom))
+	case balancedMode:
+		// keep CPU and memory usage proportional to limit fragmentation
+		cpu, memory := utilizationWith(nodeinfo, pod)
+		score = int64((1 - math.Abs(cpu-memory)/2) * float64(framework.MaxNodeScore))
+		notes = append(notes, fmt.Sprintf("cpu utilization %.2f", cpu), fmt.Sprintf("memory utilization %.2f", memory))
+	case dominantMode:
+		// score by the scarcer of CPU and memory, as in dominant resource fairness
+		cpu, memory := utilizationWith(nodeinfo, pod)
+		dominant := int64(math.Max(cpu, memory) * float64(framework.MaxNodeScore))
+		score = framework.MaxNodeScore - dominant
+		if cs.dominantPreference == mostMode {
+			score = dominant
+		}
+		notes = append(notes, fmt.Sprintf("cpu utilization %.2f", cpu), fmt.Sprintf("memory utilization %.2f", memory))
+	case binPackMode:
+		// prefer fuller nodes so empty ones can be scaled down
+		if allocatable := memoryScore(nodeinfo); allocatable > 0 {
+			score = (nodeinfo.Requested.Memory * 100) / allocatable
+		}
+		notes = append(notes, fmt.Sprintf("memory requested %d of %d", nodeinfo.Requested.Memory, memoryScore(nodeinfo)))
+	default:
+		// modes added by RegisterScorer
+		if scorer := cs.scorerFor(mode); scorer != nil {
+			if score, err = scorer.Score(nodeinfo, pod); err != nil {
+				return 0, framework.AsStatus(fmt.Errorf("error scoring node %s: %v", nodeName, err))
+			}
+			notes = append(notes, fmt.Sprintf("registered score %d", score))
+		}
+	}
+	if overridden {
+		notes = append(notes, fmt.Sprintf("allocatable memory overridden to %d", override))
+	}
+	if cs.scoreCurve != "" && cs.scoreCurve != linearCurve {
+		notes = append(notes, "curve "+cs.scoreCurve)
+	}
+	if cs.priorityWeighted {
+		factor := priorityFactor(pod)
+		score = int64(float64(score) * factor)
+		notes = append(notes, fmt.Sprintf("priority factor %.2f", factor))
+	}
+	score, notes = cs.capComponent(resourceComponent, score, notes)
+
+	// avoid freshly joined nodes while others are available
+	if since, ok := cs.readySince(nodeinfo.Node()); ok && cs.now().Sub(since) < cs.coldNodeWindow {
+		penalty := score * cs.coldNodePenaltyPercent / 100
+		if penalty < 0 {
+			penalty = -penalty
+		}
+		score -= penalty
+		notes = append(notes, fmt.Sprintf("cold node -%d", penalty))
+	}
+
+	// the kubelet may evict from nodes under pressure
+	if cond, ok := nodePressure(nodeinfo.Node()); ok && cs.pressurePenaltyPercent > 0 {
+		penalty := score * cs.pressurePenaltyPercent / 100
+		if penalty < 0 {
+			penalty = -penalty
+		}
+		score -= penalty
+		notes = append(notes, fmt.Sprintf("%s -%d", cond, penalty))
+	}
+
+	// spread away from nodes piling up small pods
+	if cs.podCountAware && len(nodeinfo.Pods) > 0 {
+		penalty := int64(math.Abs(float64(score)) * podSlotsUsed(nodeinfo))
+		score -= penalty
+		notes = append(notes, fmt.Sprintf("%d pods -%d", len(nodeinfo.Pods), penalty))
+	}
+
+	// steer pods away from expensive nodes
+	if cost, ok := cs.nodeCost(nodeinfo.Node()); ok {
+		var penalty int64
+		penalty, notes = cs.capComponent(costComponent, int64(cost*float64(cs.costWeightFor(pod))), notes)
+		score -= penalty
+		notes = append(notes, fmt.Sprintf("cost -%d", penalty))
+	}
+
+	// favor nodes carrying the preferred labels
+	var affinity int64
+	if cs.matchesPreferredNodeLabels(nodeinfo.Node()) {
+		bonus := score * cs.preferredWeightFor(pod) / 100
+		if bonus < 0 {
+			bonus = -bonus
+		}
+		affinity += bonus
+		notes = append(notes, fmt.Sprintf("preferred labels +%d", bonus))
+	}
+
+	// keep the gang together, in the zone its first members landed in
+	if d, ok := readScoreState(state); ok && d.groupZone != "" {
+		if node := nodeinfo.Node(); node != nil && node.Labels[v1.LabelTopologyZone] == d.groupZone {
+			bonus := score * groupZoneBonusPercent / 100
+			if bonus < 0 {
+				bonus = -bonus
+			}
+			affinity += bonus
+			notes = append(notes, fmt.Sprintf("group zone %s +%d", d.groupZone, bonus))
+		}
+	}
+
+	// softly prefer nodes carrying the preferred label
+	if cs.preferredLabelKey != "" {
+		if node := nodeinfo.Node(); node != nil {
+			if v, ok := node.Labels[cs.preferredLabelKey]; ok && v == cs.preferredLabelValue {
+				affinity += cs.preferredBonus
+				notes = append(notes, fmt.Sprintf("preferred label +%d", cs.preferredBonus))
+			}
+		}
+	}
+	affinity, notes = cs.capComponent(affinityComponent, affinity, notes)
+	score += affinity
+
+	if cs.scoreClamp > 0 {
+		switch {
+		case score > cs.scoreClamp:
+			score = cs.scoreClamp
+			notes = append(notes, fmt.Sprintf("clamped to %d", score))
+		case score < -cs.scoreClamp:
+			score = -cs.scoreClamp
+			notes = append(notes, fmt.Sprintf("clamped to %d", score))
+		}
+	}
+	notes = append(notes, fmt.Sprintf("score %d", score))
+	recordExplanation(state, nodeName, notes)
 
-	allocateableMemory := nodeinfo.Allocatable.Memory
+	// keep equal-memory nodes in a consistent order across cycles
+	// without NormalizeScore the framework takes the score as is, there
+	// is no room for the tiebreak
+	if cs.skipNormalize {
+		score = inNodeScoreRange(score)
+	} else if cs.stableTiebreak {
+		score = score*tiebreakSlots + nodeTiebreak(nodeName)
+	}
+	recordScore(state, nodeName, score)
+	observeRawScore(state, score)
+	return score, nil
+}
 
-	if cs.scoreMode == leastMode {
-		return -allocateableMemory, nil
-	} else if cs.scoreMode == mostMode {
-		return allocateableMemory, nil
+// capComponent bounds the magnitude of a score component to its configured
+// cap, noting when the cap applies.
+func (cs *CustomScheduler) capComponent(component string, value int64, notes []string) (int64, []string) {
+	limit, ok := cs.componentCaps[component]
+	if !ok || (value <= limit && value >= -limit) {
+		return value, notes
+	}
+	if value > limit {
+		value = limit
 	} else {
-		return 0, nil
+		value = -limit
+	}
+	return value, append(notes, fmt.Sprintf("%s capped to %d", component, value))
+}
+
+// maxUserPriority is the highest priority a user-defined PriorityClass can
+// have, the system classes above it are treated the same.
+const maxUserPriority = 1000000000
+
+// priorityFactor maps the pod's priority onto [1,2]: pods without a priority
+// or with a negative one get 1, pods at or above the highest user-defined
+// priority get 2, and priorities in between scale linearly.
+func priorityFactor(pod *v1.Pod) float64 {
+	if pod.Spec.Priority == nil || *pod.Spec.Priority <= 0 {
+		return 1
+	}
+	return 1 + math.Min(float64(*pod.Spec.Priority)/maxUserPriority, 1)
+}
+
+// readySince returns when the node's Ready condition last turned true. ok is
+// false when cold nodes aren't penalized or the node isn't Ready.
+func (cs *CustomScheduler) readySince(node *v1.Node) (time.Time, bool) {
+	if cs.coldNodeWindow <= 0 || node == nil {
+		return time.Time{}, false
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == v1.NodeReady && cond.Status == v1.ConditionTrue {
+			return cond.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// matchesPreferredNodeLabels reports whether the node carries every preferred
+// label. It is false when no preferred labels are configured.
+func (cs *CustomScheduler) matchesPreferredNodeLabels(node *v1.Node) bool {
+	if len(cs.preferredNodeLabels) == 0 || node == nil {
+		return false
+	}
+	for k, v := range cs.preferredNodeLabels {
+		if got, ok := node.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeCost reads the node's cost from the cost label. ok is false when no
+// cost label is configured or the node's value is missing or isn't a
+// non-negative number, in which case the cost doesn't affect the score.
+func (cs *CustomScheduler) nodeCost(node *v1.Node) (float64, bool) {
+	if cs.costLabel == "" || node == nil {
+		return 0, false
+	}
+	value, exists := node.Labels[cs.costLabel]
+	if !exists {
+		return 0, false
+	}
+	cost, err := strconv.ParseFloat(value, 64)
+	if err != nil || cost < 0 || math.IsNaN(cost) || math.IsInf(cost, 0) {
+		log.Printf("Warning: node %s has invalid %s label %q, ignoring its cost.", node.Name, cs.costLabel, value)
+		return 0, false
+	}
+	return cost, true
+}
+
+// gpuMemoryResourceName returns the scalar resource holding GPU memory.
+func (cs *CustomScheduler) gpuMemoryResourceName() v1.ResourceName {
+	if cs.gpuMemoryResource == "" {
+		return defaultGPUMemoryResource
+	}
+	return cs.gpuMemoryResource
+}
+
+// nodeAnnotationValue parses the integer value of the scored annotation on
+// the node. ok is false when the annotation is missing or isn't an integer.
+func (cs *CustomScheduler) nodeAnnotationValue(node *v1.Node) (int64, bool) {
+	if node == nil {
+		return 0, false
+	}
+	raw, exists := node.Annotations[cs.annotationKey]
+	if !exists {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil {
+		log.Printf("Warning: node %s has invalid %s annotation %q, giving it the minimum score.", node.Name, cs.annotationKey, raw)
+		return 0, false
+	}
+	return value, true
+}
+
+// parsePreferredNodeLabel splits a key=value label.
+func parsePreferredNodeLabel(label string) (string, string, error) {
+	key, value, found := strings.Cut(label, "=")
+	if !found || key == "" {
+		return "", "", fmt.Errorf("expected key=value, got %q", label)
+	}
+	return key, value, nil
+}
+
+// modeFor returns the mode used to score the pod. A valid scoreMode label on
+// the pod overrides the mode of its scoring profile, which overrides the mode
+// of its namespace, which in turn overrides the configured mode for its
+// scoring cycle.
+func (cs *CustomScheduler) modeFor(pod *v1.Pod) ScoreMode {
+	fallback := cs.scoreMode
+	if mode, ok := cs.namespaceModes[pod.Namespace]; ok {
+		fallback = mode
+	}
+	if profile, ok := cs.profileFor(pod); ok && profile.Mode != "" {
+		fallback = profile.Mode
+	}
+	label, exists := pod.Labels[scoreModeLabel]
+	if !exists {
+		return fallback
+	}
+	if mode := ScoreMode(label); isValidMode(mode) {
+		// Validate can't see the labels, raw scores would saturate
+		if cs.skipNormalize && scoresRawQuantity(mode) {
+			if _, warned := cs.rawModeLabels.LoadOrStore(mode, struct{}{}); !warned {
+				log.Printf("Warning: pod %s has %s label %s, which requires normalizeScores, using mode %s.", pod.Name, scoreModeLabel, mode, fallback)
+			}
+			return fallback
+		}
+		return mode
+	}
+	log.Printf("Warning: pod %s has invalid %s label %q, using mode %s.", pod.Name, scoreModeLabel, label, fallback)
+	return fallback
+}
+
+// nodeTiebreak derives a stable value in [0, tiebreakSlots) from the node name.
+func nodeTiebreak(nodeName string) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(nodeName))
+	return int64(h.Sum32()) % tiebreakSlots
+}
+
+// memoryScore returns the node's allocatable memory in bytes, the figure
+// every memory based mode starts from. NodeInfo keeps memory as plain bytes,
+// not as a quantity with a unit, so the raw scores of the memory modes are in
+// bytes too: Most scores it as is and Least negates it, so the node with the
+// least memory has the highest raw score. NormalizeScore then maps the raw
+// scores linearly to the framework's range, whatever their magnitude.
+func memoryScore(nodeinfo *framework.NodeInfo) int64 {
+	return nodeinfo.Allocatable.Memory
+}
+
+// memoryValue returns the memory figure the Least and Most modes score by.
+func (cs *CustomScheduler) memoryValue(nodeinfo *framework.NodeInfo, pod *v1.Pod) int64 {
+	if cs.scoreByFor(pod) != podCapacityScoreBy {
+		switch {
+		case cs.simulatePlacement:
+			return freeMemoryAfter(nodeinfo, pod)
+		case cs.freeMemoryBasis == allocatableMinusRequestedBasis:
+			return freeMemory(nodeinfo)
+		}
+		return memoryScore(nodeinfo)
+	}
+	request := podMemoryRequest(pod)
+	if request == 0 {
+		request = 1
+	}
+	return freeMemory(nodeinfo) / request
+}
+
+// freeMemory returns the node's memory its pods don't request yet.
+func freeMemory(nodeinfo *framework.NodeInfo) int64 {
+	free := memoryScore(nodeinfo) - nodeinfo.Requested.Memory
+	if free < 0 {
+		return 0
+	}
+	return free
+}
+
+// freeMemoryAfter returns the node's memory left free once the pod is
+// placed on it, zero when the pod doesn't fit.
+func freeMemoryAfter(nodeinfo *framework.NodeInfo, pod *v1.Pod) int64 {
+	free := freeMemory(nodeinfo) - podMemoryRequest(pod)
+	if free < 0 {
+		return 0
+	}
+	return free
+}
+
+// podHeadroom returns how many more pods the node accepts before reaching
+// its max-pods limit.
+func podHeadroom(nodeinfo *framework.NodeInfo) int64 {
+	headroom := int64(nodeinfo.Allocatable.AllowedPodNumber - len(nodeinfo.Pods))
+	if headroom < 0 {
+		return 0
+	}
+	return headroom
+}
+
+// defaultMaxPods is the kubelet's default max-pods limit, assumed for nodes
+// that don't report one.
+const defaultMaxPods = 110
+
+// podSlotsUsed returns the fraction of the node's max-pods limit its pods
+// take, capped at 1.
+func podSlotsUsed(nodeinfo *framework.NodeInfo) float64 {
+	slots := nodeinfo.Allocatable.AllowedPodNumber
+	if slots <= 0 {
+		slots = defaultMaxPods
+	}
+	return math.Min(float64(len(nodeinfo.Pods))/float64(slots), 1)
+}
+
+// utilizationWith returns the fraction of the node's CPU and memory that
+// would be requested once the pod is placed on it, capped at 1.
+func utilizationWith(nodeinfo *framework.NodeInfo, pod *v1.Pod) (float64, float64) {
+	fraction := func(requested, allocatable int64) float64 {
+		if allocatable <= 0 {
+			return 0
+		}
+		return math.Min(float64(requested)/float64(allocatable), 1)
+	}
+	cpu := fraction(nodeinfo.Requested.MilliCPU+podCPURequest(pod), nodeinfo.Allocatable.MilliCPU)
+	memory := fraction(nodeinfo.Requested.Memory+podMemoryRequest(pod), memoryScore(nodeinfo))
+	return cpu, memory
+}
+
+// podCPURequest sums the CPU requests of the pod's containers in millicores.
+func podCPURequest(pod *v1.Pod) int64 {
+	var total int64
+	for _, c := range pod.Spec.Containers {
+		total += c.Resources.Requests.Cpu().MilliValue()
+	}
+	return total
+}
+
+// podScalarRequest sums the requests of the scalar resource over the pod's
+// containers.
+func podScalarRequest(pod *v1.Pod, name v1.ResourceName) int64 {
+	var total int64
+	for _, c := range pod.Spec.Containers {
+		if q, ok := c.Resources.Requests[name]; ok {
+			total += q.Value()
+		}
+	}
+	return total
+}
+
+// podMemoryRequest sums the memory requests of the pod's containers.
+func podMemoryRequest(pod *v1.Pod) int64 {
+	var total int64
+	for _, c := range pod.Spec.Containers {
+		total += c.Resources.Requests.Memory().Value()
+	}
+	return total
+}
+
+// applyCurve reshapes the raw resource value before the mode sign is applied.
+// The log curve compresses large values so a few huge nodes don't dominate,
+// the step curve buckets values into tiers of stepCurveBucket.
+func applyCurve(curve string, value int64) int64 {
+	if value < 0 {
+		value = 0
+	}
+	switch curve {
+	case logCurve:
+		return int64(math.Log1p(float64(value)) * 1000)
+	case stepCurve:
+		return value / stepCurveBucket
+	default:
+		return value
 	}
 }
 
@@ -130,30 +1787,176 @@ func (cs *CustomScheduler) NormalizeScore(ctx context.Context, state *framework.
 	// TODO
 	// find the range of the current score and map to the valid range
 
-	minScore := int64(1000000)
-	maxScore := int64(-1000000)
-	for _, score := range scores {
-		if score.Score > maxScore {
-			maxScore = score.Score
+	scoreState, hasScoreState := readScoreState(state)
+	mode := cs.modeFor(pod)
+	if hasScoreState {
+		mode = scoreState.mode
+	}
+	if mode == neutralMode {
+		return framework.NewStatus(framework.Success)
+	}
+
+	if klog.V(5).Enabled() {
+		raw := make([]int64, len(scores))
+		for i := range scores {
+			raw[i] = scores[i].Score
 		}
-		if score.Score < minScore {
-			minScore = score.Score
+		defer logScoreTable(pod, raw, scores)
+	}
+
+	// Score tracked the range when PreScore ran, otherwise find it
+	var minScore, maxScore int64
+	var ranged bool
+	if hasScoreState {
+		minScore, maxScore, ranged = scoreState.rawRange()
+		klog.V(5).Infof("Normalizing the scores of pod %s/%s by mode %s on %s, raw range [%d,%d].", pod.Namespace, pod.Name, mode, scoreState.resource, minScore, maxScore)
+	}
+	if !ranged {
+		// each chunk finds its own range, merged once they're all done
+		chunks := scoreChunks(len(scores))
+		mins, maxs := make([]int64, chunks), make([]int64, chunks)
+		for chunk := range mins {
+			mins[chunk], maxs[chunk] = math.MaxInt64, math.MinInt64
+		}
+		cs.forEachScoreChunk(ctx, len(scores), func(chunk, start, end int) {
+			for _, score := range scores[start:end] {
+				if score.Score == worstScore {
+					continue
+				}
+				if score.Score > maxs[chunk] {
+					maxs[chunk] = score.Score
+				}
+				if score.Score < mins[chunk] {
+					mins[chunk] = score.Score
+				}
+			}
+		})
+		minScore, maxScore = math.MaxInt64, math.MinInt64
+		for chunk := range mins {
+			if mins[chunk] < minScore {
+				minScore = mins[chunk]
+			}
+			if maxs[chunk] > maxScore {
+				maxScore = maxs[chunk]
+			}
 		}
 	}
 
-	// incase division by zero
-	if minScore == maxScore {
-		return framework.NewStatus(framework.Success)
+	lo, hi := cs.normalizeBounds()
+	ratio, short := gangShortfallRatio(state)
+	cs.forEachScoreChunk(ctx, len(scores), func(_, start, end int) {
+		for i := start; i < end; i++ {
+			switch {
+			case scores[i].Score == worstScore:
+				scores[i].Score = lo
+			// incase division by zero
+			case minScore != maxScore:
+				scores[i].Score = lo + ((scores[i].Score-minScore)*(hi-lo))/(maxScore-minScore)
+			}
+			// the mapping overflows, or is skipped, for raw scores Score
+			// shouldn't produce, don't leave the clamping to the framework
+			if scores[i].Score < lo || scores[i].Score > hi {
+				log.Printf("Warning: normalized score %d of node %s for pod %s is out of range, clamping it.", scores[i].Score, scores[i].Name, pod.Name)
+				scoreClampedTotal.Inc()
+				if scores[i].Score < lo {
+					scores[i].Score = lo
+				} else {
+					scores[i].Score = hi
+				}
+			}
+			// disprefer every node for pods of under-quota groups
+			if short {
+				scores[i].Score = lo + int64(float64(scores[i].Score-lo)*ratio)
+			}
+		}
+	})
+
+	return framework.NewStatus(framework.Success)
+}
+
+// normalizeBounds returns the range NormalizeScore maps the raw scores to.
+func (cs *CustomScheduler) normalizeBounds() (int64, int64) {
+	if cs.normalizeMin == 0 && cs.normalizeMax == 0 {
+		return framework.MinNodeScore, framework.MaxNodeScore
 	}
+	return cs.normalizeMin, cs.normalizeMax
+}
+
+// normalizeChunkSize is the number of scores a NormalizeScore worker handles
+// at a time. Lists no longer than one chunk are handled inline, the
+// goroutines would cost more than they save.
+const normalizeChunkSize = 1024
 
-	for i := range scores {
-		scores[i].Score = ((scores[i].Score - minScore) * 100) / (maxScore - minScore)
+// scoreChunks returns the number of chunks n scores are split into.
+func scoreChunks(n int) int {
+	return (n + normalizeChunkSize - 1) / normalizeChunkSize
+}
+
+// forEachScoreChunk runs work over consecutive chunks of n scores, on the
+// framework's parallelizer when there's more than one. Chunks are fixed by n
+// alone, so whatever work reduces per chunk comes out the same every run.
+func (cs *CustomScheduler) forEachScoreChunk(ctx context.Context, n int, work func(chunk, start, end int)) {
+	chunks := scoreChunks(n)
+	piece := func(chunk int) {
+		start := chunk * normalizeChunkSize
+		end := start + normalizeChunkSize
+		if end > n {
+			end = n
+		}
+		work(chunk, start, end)
 	}
+	if chunks <= 1 || cs.handle == nil {
+		for chunk := 0; chunk < chunks; chunk++ {
+			piece(chunk)
+		}
+		return
+	}
+	cs.handle.Parallelizer().Until(ctx, chunks, piece, Name)
+}
 
-	return framework.NewStatus(framework.Success)
+// logScoreTable dumps the raw and normalized score of every node for the pod,
+// highest normalized score first.
+func logScoreTable(pod *v1.Pod, raw []int64, scores framework.NodeScoreList) {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if scores[order[a]].Score != scores[order[b]].Score {
+			return scores[order[a]].Score > scores[order[b]].Score
+		}
+		return scores[order[a]].Name < scores[order[b]].Name
+	})
+	klog.V(5).Infof("Score table for pod %s/%s:", pod.Namespace, pod.Name)
+	for _, i := range order {
+		klog.V(5).Infof("  node %s: raw=%d normalized=%d", scores[i].Name, raw[i], scores[i].Score)
+	}
 }
 
 // ScoreExtensions of the Score plugin.
 func (cs *CustomScheduler) ScoreExtensions() framework.ScoreExtensions {
+	if cs.skipNormalize {
+		return nil
+	}
 	return cs
 }
+
+// unscorableScore returns the score of nodes that can't be scored, the
+// framework takes it as is when NormalizeScore is off.
+func (cs *CustomScheduler) unscorableScore() int64 {
+	if cs.skipNormalize {
+		return framework.MinNodeScore
+	}
+	return worstScore
+}
+
+// inNodeScoreRange clamps the score to the framework's range.
+func inNodeScoreRange(score int64) int64 {
+	switch {
+	case score < framework.MinNodeScore:
+		return framework.MinNodeScore
+	case score > framework.MaxNodeScore:
+		return framework.MaxNodeScore
+	}
+	return score
+}
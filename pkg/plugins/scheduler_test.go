@@ -1,24 +1,39 @@
 package plugins
 
 import (
+	"bytes"
 	"context"
+	"flag"
 	"fmt"
+	"io"
+	"log"
 	"math"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"go.uber.org/goleak"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/component-base/metrics/testutil"
+	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	fakeframework "k8s.io/kubernetes/pkg/scheduler/framework/fake"
 	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
 	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
 	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
 	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
 )
 
 func TestCustomScheduler_PreFilter(t *testing.T) {
@@ -143,6 +158,1227 @@ func TestCustomScheduler_PreFilter(t *testing.T) {
 	}
 }
 
+func TestCustomScheduler_PreFilterSchedulingGates(t *testing.T) {
+	tests := []struct {
+		name         string
+		minAvailable string
+		want         framework.Code
+		wantMsg      string
+	}{
+		{name: "ungated members meet minAvailable", minAvailable: "2", want: framework.Success},
+		{name: "gated members are not counted", minAvailable: "3", want: framework.Unschedulable, wantMsg: "2 present, minimum required is 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(client, 0)
+			podInformer := informerFactory.Core().V1().Pods()
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			}
+			fh, err := st.NewFramework(
+				registeredPlugins,
+				"default-scheduler",
+				wait.NeverStop,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithInformerFactory(informerFactory),
+			)
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			cs := &CustomScheduler{
+				handle:    fh,
+				scoreMode: leastMode,
+			}
+
+			// Two ungated and two gated members.
+			for i := 0; i < 4; i++ {
+				pod := &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   fmt.Sprintf("pod%d", i),
+						Labels: map[string]string{"podGroup": "g1"},
+					},
+					Spec: v1.PodSpec{Containers: []v1.Container{}},
+				}
+				if i%2 == 1 {
+					pod.Spec.SchedulingGates = []v1.PodSchedulingGate{{Name: "example.com/hold"}}
+				}
+				podInformer.Informer().GetStore().Add(pod)
+			}
+
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"podGroup":     "g1",
+						"minAvailable": tt.minAvailable,
+					},
+				},
+				Spec: v1.PodSpec{Containers: []v1.Container{}},
+			}
+			_, status := cs.PreFilter(context.Background(), nil, pod)
+			if status.Code() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, status.Code())
+			}
+			if !strings.Contains(status.Message(), tt.wantMsg) {
+				t.Errorf("expected message to contain %q, got %q", tt.wantMsg, status.Message())
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterHardFail(t *testing.T) {
+	tests := []struct {
+		name string
+		age  time.Duration
+		want framework.Code
+	}{
+		{name: "before the deadline", age: 10 * time.Second, want: framework.Unschedulable},
+		{name: "after the deadline", age: 2 * time.Minute, want: framework.UnschedulableAndUnresolvable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(client, 0)
+			podInformer := informerFactory.Core().V1().Pods()
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			}
+			fh, err := st.NewFramework(
+				registeredPlugins,
+				"default-scheduler",
+				wait.NeverStop,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithInformerFactory(informerFactory),
+			)
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			cs := &CustomScheduler{
+				handle:        fh,
+				scoreMode:     leastMode,
+				hardFailAfter: time.Minute,
+			}
+
+			created := metav1.NewTime(time.Now().Add(-tt.age))
+			podInformer.Informer().GetStore().Add(&v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "pod0",
+					CreationTimestamp: created,
+					Labels:            map[string]string{"podGroup": "g1"},
+				},
+			})
+
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "pod1",
+					CreationTimestamp: metav1.Now(),
+					Labels: map[string]string{
+						"podGroup":     "g1",
+						"minAvailable": "3",
+					},
+				},
+				Spec: v1.PodSpec{Containers: []v1.Container{}},
+			}
+			_, status := cs.PreFilter(context.Background(), nil, pod)
+			if status.Code() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, status.Code())
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterGroupStabilization(t *testing.T) {
+	tests := []struct {
+		name string
+		age  time.Duration
+		want framework.Code
+	}{
+		{name: "group younger than the threshold", age: 5 * time.Second, want: framework.Unschedulable},
+		{name: "group older than the threshold", age: time.Minute, want: framework.Success},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(client, 0)
+			podInformer := informerFactory.Core().V1().Pods()
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			}
+			fh, err := st.NewFramework(
+				registeredPlugins,
+				"default-scheduler",
+				wait.NeverStop,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithInformerFactory(informerFactory),
+			)
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			cs := &CustomScheduler{
+				handle:             fh,
+				scoreMode:          leastMode,
+				groupStabilization: 30 * time.Second,
+			}
+
+			for i := 0; i < 2; i++ {
+				podInformer.Informer().GetStore().Add(&v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              fmt.Sprintf("pod%d", i),
+						CreationTimestamp: metav1.NewTime(time.Now().Add(-tt.age)),
+						Labels:            map[string]string{"podGroup": "g1"},
+					},
+				})
+			}
+
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "pod2",
+					CreationTimestamp: metav1.Now(),
+					Labels: map[string]string{
+						"podGroup":     "g1",
+						"minAvailable": "2",
+					},
+				},
+			}
+			_, status := cs.PreFilter(context.Background(), nil, pod)
+			if status.Code() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, status.Code())
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterDaemonSetPods(t *testing.T) {
+	groupLabels := map[string]string{
+		"podGroup":     "g1",
+		"minAvailable": "5",
+	}
+	tests := []struct {
+		name              string
+		skipDaemonSetPods bool
+		pod               *v1.Pod
+		want              framework.Code
+	}{
+		{
+			name:              "daemonset pod is skipped",
+			skipDaemonSetPods: true,
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "ds-pod",
+					Labels:          groupLabels,
+					OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "ds"}},
+				},
+			},
+			want: framework.Success,
+		},
+		{
+			name:              "mirror pod is skipped",
+			skipDaemonSetPods: true,
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "static-pod",
+					Labels:      groupLabels,
+					Annotations: map[string]string{v1.MirrorPodAnnotationKey: "hash"},
+				},
+			},
+			want: framework.Success,
+		},
+		{
+			name:              "daemonset pod is gated when skipping is disabled",
+			skipDaemonSetPods: false,
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "ds-pod",
+					Labels:          groupLabels,
+					OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "ds"}},
+				},
+			},
+			want: framework.Unschedulable,
+		},
+		{
+			name:              "replicaset pod is gated",
+			skipDaemonSetPods: true,
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "rs-pod",
+					Labels:          groupLabels,
+					OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs"}},
+				},
+			},
+			want: framework.Unschedulable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(client, 0)
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			}
+			fh, err := st.NewFramework(
+				registeredPlugins,
+				"default-scheduler",
+				wait.NeverStop,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithInformerFactory(informerFactory),
+			)
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			cs := &CustomScheduler{
+				handle:            fh,
+				scoreMode:         leastMode,
+				skipDaemonSetPods: tt.skipDaemonSetPods,
+			}
+
+			_, status := cs.PreFilter(context.Background(), nil, tt.pod)
+			if status.Code() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, status.Code())
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterGroupBy(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   framework.Code
+	}{
+		{
+			name:   "group defined by both keys is complete",
+			labels: map[string]string{"app": "train", "release": "r1", "minAvailable": "3"},
+			want:   framework.Success,
+		},
+		{
+			name:   "pods differing in release are a separate group",
+			labels: map[string]string{"app": "train", "release": "r2", "minAvailable": "2"},
+			want:   framework.Unschedulable,
+		},
+		{
+			name:   "pods differing in app are a separate group",
+			labels: map[string]string{"app": "serve", "release": "r1", "minAvailable": "2"},
+			want:   framework.Unschedulable,
+		},
+		{
+			name:   "pod missing a group key",
+			labels: map[string]string{"app": "train", "minAvailable": "1"},
+			want:   framework.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(client, 0)
+			podInformer := informerFactory.Core().V1().Pods()
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			}
+			fh, err := st.NewFramework(
+				registeredPlugins,
+				"default-scheduler",
+				wait.NeverStop,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithInformerFactory(informerFactory),
+			)
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			cs := &CustomScheduler{
+				handle:    fh,
+				scoreMode: leastMode,
+				groupBy:   []string{"app", "release"},
+			}
+
+			members := []map[string]string{
+				{"app": "train", "release": "r1"},
+				{"app": "train", "release": "r1"},
+				{"app": "train", "release": "r1"},
+				{"app": "train", "release": "r2"},
+				{"app": "serve", "release": "r1"},
+			}
+			for i, l := range members {
+				podInformer.Informer().GetStore().Add(&v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod%d", i), Labels: l},
+				})
+			}
+
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "incoming", Labels: tt.labels}}
+			_, status := cs.PreFilter(context.Background(), nil, pod)
+			if status.Code() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, status.Code())
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterGroupNameLabels(t *testing.T) {
+	const newKey = "nthu.io/group"
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   framework.Code
+	}{
+		{
+			name:   "pod on the old key",
+			labels: map[string]string{"podGroup": "g1", "minAvailable": "3"},
+			want:   framework.Success,
+		},
+		{
+			name:   "pod on the new key",
+			labels: map[string]string{newKey: "g1", "minAvailable": "3"},
+			want:   framework.Success,
+		},
+		{
+			name:   "pod on both keys",
+			labels: map[string]string{newKey: "g1", "podGroup": "g1", "minAvailable": "3"},
+			want:   framework.Success,
+		},
+		{
+			name:   "group short of minAvailable",
+			labels: map[string]string{"podGroup": "g1", "minAvailable": "4"},
+			want:   framework.Unschedulable,
+		},
+		{
+			name:   "the first key wins over the old one",
+			labels: map[string]string{newKey: "g2", "podGroup": "g1", "minAvailable": "2"},
+			want:   framework.Unschedulable,
+		},
+	}
+	members := []map[string]string{
+		{"podGroup": "g1"},
+		{newKey: "g1"},
+		{newKey: "g1", "podGroup": "g2"},
+		// in g2 by the new key, despite the old one
+		{newKey: "g2", "podGroup": "g1"},
+	}
+	var pods []*v1.Pod
+	for i, l := range members {
+		pods = append(pods, &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod%d", i), Labels: l}})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, nil, pods)
+			cs := &CustomScheduler{
+				handle:          fh,
+				scoreMode:       leastMode,
+				groupNameLabels: splitLabelKeys([]string{newKey + ",podGroup"}),
+			}
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "incoming", Labels: tt.labels}}
+			_, status := cs.PreFilter(context.Background(), nil, pod)
+			if status.Code() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, status.Code())
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterExcludedNamespaces(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      string
+		namespace string
+		want      framework.Code
+	}{
+		{name: "kube-system passes by default", args: `{"mode": "Least"}`, namespace: "kube-system", want: framework.Success},
+		{name: "kube-public passes by default", args: `{"mode": "Least"}`, namespace: "kube-public", want: framework.Success},
+		{name: "other namespaces gated", args: `{"mode": "Least"}`, namespace: "default", want: framework.Unschedulable},
+		{name: "configured namespace passes", args: `{"mode": "Least", "excludedNamespaces": ["batch"]}`, namespace: "batch", want: framework.Success},
+		{name: "defaults replaced", args: `{"mode": "Least", "excludedNamespaces": ["batch"]}`, namespace: "kube-system", want: framework.Unschedulable},
+		{name: "nothing excluded", args: `{"mode": "Least", "excludedNamespaces": []}`, namespace: "kube-system", want: framework.Unschedulable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(&runtime.Unknown{Raw: []byte(tt.args)}, nil)
+			if err != nil {
+				t.Fatalf("fail to create plugin: %s", err)
+			}
+			cs := p.(*CustomScheduler)
+			cs.handle = newTestHandle(t, nil, nil)
+			// labeled as a gang that can't complete
+			pod := st.MakePod().Name("coredns").Namespace(tt.namespace).
+				Label("podGroup", "dns").Label("minAvailable", "3").Phase(v1.PodPending).Obj()
+			_, status := cs.PreFilter(context.Background(), nil, pod)
+			if status.Code() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, status)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterGroupSelectorExpressions(t *testing.T) {
+	tests := []struct {
+		name         string
+		expressions  []metav1.LabelSelectorRequirement
+		minAvailable string
+		want         framework.Code
+	}{
+		{
+			name:         "In counts the listed tiers",
+			expressions:  []metav1.LabelSelectorRequirement{{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"gold", "silver"}}},
+			minAvailable: "3",
+			want:         framework.Success,
+		},
+		{
+			name:         "In excludes other tiers",
+			expressions:  []metav1.LabelSelectorRequirement{{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"gold"}}},
+			minAvailable: "3",
+			want:         framework.Unschedulable,
+		},
+		{
+			name:         "NotIn excludes the listed tier",
+			expressions:  []metav1.LabelSelectorRequirement{{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"gold"}}},
+			minAvailable: "3",
+			want:         framework.Unschedulable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(client, 0)
+			podInformer := informerFactory.Core().V1().Pods()
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			}
+			fh, err := st.NewFramework(
+				registeredPlugins,
+				"default-scheduler",
+				wait.NeverStop,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithInformerFactory(informerFactory),
+			)
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchExpressions: tt.expressions})
+			if err != nil {
+				t.Fatalf("fail to build selector: %s", err)
+			}
+			cs := &CustomScheduler{
+				handle:        fh,
+				scoreMode:     leastMode,
+				groupSelector: selector,
+			}
+
+			// two gold members, one silver member and one in another group
+			members := []map[string]string{
+				{"podGroup": "g1", "tier": "gold"},
+				{"podGroup": "g1", "tier": "gold"},
+				{"podGroup": "g1", "tier": "silver"},
+				{"podGroup": "g2", "tier": "silver"},
+			}
+			for i, l := range members {
+				podInformer.Informer().GetStore().Add(&v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod%d", i), Labels: l},
+				})
+			}
+
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "incoming",
+					Labels: map[string]string{
+						"podGroup":     "g1",
+						"minAvailable": tt.minAvailable,
+					},
+				},
+			}
+			_, status := cs.PreFilter(context.Background(), nil, pod)
+			if status.Code() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, status.Code())
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterCountPhases(t *testing.T) {
+	tests := []struct {
+		name        string
+		countPhases []string
+		want        framework.Code
+	}{
+		{name: "default phases skip the phase-less pod", countPhases: defaultCountPhases, want: framework.Unschedulable},
+		{name: "empty phase counts when configured", countPhases: []string{"", "Pending", "Running"}, want: framework.Success},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(client, 0)
+			podInformer := informerFactory.Core().V1().Pods()
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			}
+			fh, err := st.NewFramework(
+				registeredPlugins,
+				"default-scheduler",
+				wait.NeverStop,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithInformerFactory(informerFactory),
+			)
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			cs := &CustomScheduler{
+				handle:      fh,
+				scoreMode:   leastMode,
+				countPhases: phaseSet(tt.countPhases),
+			}
+
+			// a freshly created pod has no phase yet
+			phases := []v1.PodPhase{v1.PodRunning, v1.PodPending, "", v1.PodSucceeded}
+			for i, phase := range phases {
+				podInformer.Informer().GetStore().Add(&v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod%d", i), Labels: map[string]string{"podGroup": "g1"}},
+					Status:     v1.PodStatus{Phase: phase},
+				})
+			}
+
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "incoming",
+					Labels: map[string]string{
+						"podGroup":     "g1",
+						"minAvailable": "3",
+					},
+				},
+			}
+			_, status := cs.PreFilter(context.Background(), nil, pod)
+			if status.Code() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, status.Code())
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterMissingLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		wantMsg string
+	}{
+		{name: "nil labels", labels: nil, wantMsg: "group label not found on pod pod0"},
+		{name: "missing minAvailable", labels: map[string]string{"podGroup": "g1"}, wantMsg: "group minAvail not found on pod pod0"},
+		{name: "non-numeric minAvailable", labels: map[string]string{"podGroup": "g1", "minAvailable": "three"}, wantMsg: `minAvailable must be a positive integer, got "three" on pod pod0`},
+		{name: "zero minAvailable", labels: map[string]string{"podGroup": "g1", "minAvailable": "0"}, wantMsg: `minAvailable must be a positive integer, got "0" on pod pod0`},
+		{name: "negative minAvailable", labels: map[string]string{"podGroup": "g1", "minAvailable": "-2"}, wantMsg: `minAvailable must be a positive integer, got "-2" on pod pod0`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(client, 0)
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			}
+			fh, err := st.NewFramework(
+				registeredPlugins,
+				"default-scheduler",
+				wait.NeverStop,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithInformerFactory(informerFactory),
+			)
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			cs := &CustomScheduler{
+				handle:    fh,
+				scoreMode: leastMode,
+			}
+
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod0", Labels: tt.labels}}
+			_, status := cs.PreFilter(context.Background(), nil, pod)
+			if status.Code() != framework.Error {
+				t.Errorf("expected %v, got %v", framework.Error, status.Code())
+			}
+			if status.Message() != tt.wantMsg {
+				t.Errorf("expected message %q, got %q", tt.wantMsg, status.Message())
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterMinAvailableWhitespace(t *testing.T) {
+	pods := []*v1.Pod{
+		st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "2 ").Obj(),
+		st.MakePod().Name("pod1").Namespace("default").Label("podGroup", "g1").Label("minAvailable", " 2").Obj(),
+	}
+	fh := newTestHandle(t, nil, pods)
+	cs := &CustomScheduler{handle: fh, scoreMode: leastMode}
+
+	for _, pod := range pods {
+		if _, status := cs.PreFilter(context.Background(), nil, pod); !status.IsSuccess() {
+			t.Errorf("expected pod %s to pass, got %v", pod.Name, status)
+		}
+	}
+}
+
+func TestCustomScheduler_PreFilterDefaultMinAvailable(t *testing.T) {
+	pods := []*v1.Pod{
+		st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "g1").Obj(),
+	}
+	fh := newTestHandle(t, nil, pods)
+
+	tests := []struct {
+		name                string
+		defaultMinAvailable int
+		want                *framework.Status
+	}{
+		{
+			name:                "default minimum of one",
+			defaultMinAvailable: 1,
+			want:                framework.NewStatus(framework.Success, ""),
+		},
+		{
+			name:                "larger default minimum",
+			defaultMinAvailable: 2,
+			want:                framework.NewStatus(framework.Unschedulable, "Not enough pods in group g1, 1 present, minimum required is 2"),
+		},
+		{
+			name: "explicit minimum required",
+			want: framework.AsStatus(fmt.Errorf("group minAvail not found on pod pod0")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode, defaultMinAvailable: tt.defaultMinAvailable}
+			_, got := cs.PreFilter(context.Background(), nil, pods[0])
+			if got.Code() != tt.want.Code() || got.Message() != tt.want.Message() {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterMinAvailableAnnotation(t *testing.T) {
+	const annotation = "example.com/min-available"
+	member := func(name string, labels, annotations map[string]string) *v1.Pod {
+		p := st.MakePod().Name(name).Namespace("default").Label("podGroup", "g1").Annotations(annotations).Obj()
+		for k, v := range labels {
+			p.Labels[k] = v
+		}
+		return p
+	}
+
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+		want *framework.Status
+	}{
+		{
+			name: "label wins over annotation",
+			pod:  member("pod0", map[string]string{"minAvailable": "1"}, map[string]string{annotation: "3"}),
+			want: framework.NewStatus(framework.Success, ""),
+		},
+		{
+			name: "annotation wins over default",
+			pod:  member("pod0", nil, map[string]string{annotation: " 3"}),
+			want: framework.NewStatus(framework.Unschedulable, "Not enough pods in group g1, 1 present, minimum required is 3"),
+		},
+		{
+			name: "default without label or annotation",
+			pod:  member("pod0", nil, nil),
+			want: framework.NewStatus(framework.Success, ""),
+		},
+		{
+			name: "malformed annotation",
+			pod:  member("pod0", nil, map[string]string{annotation: "three"}),
+			want: framework.AsStatus(fmt.Errorf(`minAvailable must be a positive integer, got "three" on pod pod0`)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, nil, []*v1.Pod{tt.pod})
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode, minAvailableAnnotation: annotation, defaultMinAvailable: 1}
+			_, got := cs.PreFilter(context.Background(), nil, tt.pod)
+			if got.Code() != tt.want.Code() || got.Message() != tt.want.Message() {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterSkipUngrouped(t *testing.T) {
+	tests := []struct {
+		name          string
+		skipUngrouped bool
+		want          framework.Code
+	}{
+		{name: "ungrouped pod passes", skipUngrouped: true, want: framework.Success},
+		{name: "ungrouped pod errors when skipping is disabled", skipUngrouped: false, want: framework.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(client, 0)
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			}
+			fh, err := st.NewFramework(
+				registeredPlugins,
+				"default-scheduler",
+				wait.NeverStop,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithInformerFactory(informerFactory),
+			)
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			cs := &CustomScheduler{
+				handle:        fh,
+				scoreMode:     leastMode,
+				skipUngrouped: tt.skipUngrouped,
+			}
+
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod0", Labels: map[string]string{"app": "web"}}}
+			_, status := cs.PreFilter(context.Background(), nil, pod)
+			if status.Code() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, status.Code())
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterGangReadyEvent(t *testing.T) {
+	client := clientsetfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	recorder := events.NewFakeRecorder(10)
+	registeredPlugins := []st.RegisterPluginFunc{
+		st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}
+	fh, err := st.NewFramework(
+		registeredPlugins,
+		"default-scheduler",
+		wait.NeverStop,
+		frameworkruntime.WithClientSet(client),
+		frameworkruntime.WithInformerFactory(informerFactory),
+		frameworkruntime.WithEventRecorder(recorder),
+	)
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+
+	cs := &CustomScheduler{
+		handle:    fh,
+		scoreMode: leastMode,
+		groups:    newGroupCounter(func(*v1.Pod) (string, bool) { return "", false }),
+	}
+
+	addMember := func(i int) {
+		podInformer.Informer().GetStore().Add(&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod%d", i), Labels: map[string]string{"podGroup": "g1"}},
+		})
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "pod0",
+			Labels: map[string]string{
+				"podGroup":     "g1",
+				"minAvailable": "3",
+			},
+		},
+	}
+
+	steps := []struct {
+		members int
+		want    framework.Code
+		events  int
+	}{
+		{members: 2, want: framework.Unschedulable, events: 0},
+		{members: 3, want: framework.Success, events: 1},
+		{members: 3, want: framework.Success, events: 0},
+	}
+	added := 0
+	for i, step := range steps {
+		for ; added < step.members; added++ {
+			addMember(added)
+		}
+		_, status := cs.PreFilter(context.Background(), nil, pod)
+		if status.Code() != step.want {
+			t.Errorf("step %d: expected %v, got %v", i, step.want, status.Code())
+		}
+		if got := len(recorder.Events); got != step.events {
+			t.Fatalf("step %d: expected %d events, got %d", i, step.events, got)
+		}
+		for j := 0; j < step.events; j++ {
+			if e := <-recorder.Events; !strings.Contains(e, "Normal GangReady") {
+				t.Errorf("step %d: expected a GangReady event, got %q", i, e)
+			}
+		}
+	}
+}
+
+func TestCustomScheduler_PreFilterMaxActiveGroups(t *testing.T) {
+	pods := []*v1.Pod{}
+	for _, group := range []string{"g1", "g2"} {
+		for i := 0; i < 2; i++ {
+			pods = append(pods, st.MakePod().Name(fmt.Sprintf("%s-pod%d", group, i)).Namespace("default").
+				UID(fmt.Sprintf("%s-uid%d", group, i)).Label("podGroup", group).Label("minAvailable", "2").Obj())
+		}
+	}
+	fh := newTestHandle(t, nil, pods)
+	cs := &CustomScheduler{handle: fh, scoreMode: leastMode, maxActiveGroups: 1}
+	cs.groups = newGroupCounter(cs.groupName)
+	for _, p := range pods {
+		cs.groups.add(p)
+	}
+
+	if _, status := cs.PreFilter(context.Background(), nil, pods[0]); !status.IsSuccess() {
+		t.Fatalf("expected the first group to pass, got %v", status)
+	}
+	_, status := cs.PreFilter(context.Background(), nil, pods[2])
+	if status.Code() != framework.Unschedulable {
+		t.Fatalf("expected the second group to wait for a slot, got %v", status)
+	}
+	// members of the active group keep passing
+	if _, status := cs.PreFilter(context.Background(), nil, pods[1]); !status.IsSuccess() {
+		t.Fatalf("expected the active group to pass, got %v", status)
+	}
+
+	// binding the whole first group frees its slot
+	for _, p := range pods[:2] {
+		bound := p.DeepCopy()
+		bound.Spec.NodeName = "m1"
+		cs.groups.update(p, bound)
+	}
+	if _, status := cs.PreFilter(context.Background(), nil, pods[2]); !status.IsSuccess() {
+		t.Fatalf("expected the second group to pass once the first is bound, got %v", status)
+	}
+}
+
+func TestCustomScheduler_PreFilterMaxActiveGroupsTTL(t *testing.T) {
+	pods := []*v1.Pod{}
+	for _, group := range []string{"g1", "g2"} {
+		for i := 0; i < 2; i++ {
+			pods = append(pods, st.MakePod().Name(fmt.Sprintf("%s-pod%d", group, i)).Namespace("default").
+				UID(fmt.Sprintf("%s-uid%d", group, i)).Label("podGroup", group).Label("minAvailable", "2").Obj())
+		}
+	}
+	fh := newTestHandle(t, nil, pods)
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	cs := &CustomScheduler{handle: fh, scoreMode: leastMode, maxActiveGroups: 1, activeGroupTTL: time.Minute, clock: fakeClock}
+	cs.groups = newGroupCounter(cs.groupName)
+	for _, p := range pods {
+		cs.groups.add(p)
+	}
+
+	// the first group passes PreFilter but none of its members gets placed
+	if _, status := cs.PreFilter(context.Background(), nil, pods[0]); !status.IsSuccess() {
+		t.Fatalf("expected the first group to pass, got %v", status)
+	}
+	fakeClock.Step(59 * time.Second)
+	if _, status := cs.PreFilter(context.Background(), nil, pods[2]); status.Code() != framework.Unschedulable {
+		t.Fatalf("expected the second group to wait for a slot, got %v", status)
+	}
+	// retrying the stuck group doesn't extend its slot
+	if _, status := cs.PreFilter(context.Background(), nil, pods[1]); !status.IsSuccess() {
+		t.Fatalf("expected the active group to pass, got %v", status)
+	}
+
+	fakeClock.Step(time.Second)
+	if _, status := cs.PreFilter(context.Background(), nil, pods[2]); !status.IsSuccess() {
+		t.Fatalf("expected the second group to pass once the first one's slot expired, got %v", status)
+	}
+	if _, status := cs.PreFilter(context.Background(), nil, pods[0]); status.Code() != framework.Unschedulable {
+		t.Errorf("expected the expired group to wait for a slot, got %v", status)
+	}
+}
+
+func TestCustomScheduler_PreFilterRoles(t *testing.T) {
+	byRole := `{"ps": 1, "worker": 2}`
+	member := func(name, role string) *v1.Pod {
+		return st.MakePod().Name(name).Namespace("default").
+			Label("podGroup", "job1").Label("podRole", role).
+			Annotation("minAvailableByRole", byRole).Obj()
+	}
+
+	tests := []struct {
+		name string
+		pods []*v1.Pod
+		want *framework.Status
+	}{
+		{
+			name: "every role satisfied",
+			pods: []*v1.Pod{member("ps0", "ps"), member("worker0", "worker"), member("worker1", "worker")},
+			want: framework.NewStatus(framework.Success, ""),
+		},
+		{
+			name: "workers short",
+			pods: []*v1.Pod{member("ps0", "ps"), member("ps1", "ps"), member("worker0", "worker")},
+			want: framework.NewStatus(framework.Unschedulable, "Not enough pods with role worker in group job1, 1 present, minimum required is 2"),
+		},
+		{
+			name: "group short of the role total",
+			pods: []*v1.Pod{member("ps0", "ps"), member("worker0", "worker")},
+			want: framework.NewStatus(framework.Unschedulable, "Not enough pods in group job1, 2 present, minimum required is 3"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, nil, tt.pods)
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode}
+			_, got := cs.PreFilter(context.Background(), nil, tt.pods[0])
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterGroupExcludeLabel(t *testing.T) {
+	pods := []*v1.Pod{
+		st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "3").Obj(),
+		st.MakePod().Name("pod1").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "3").Obj(),
+		st.MakePod().Name("debug").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "3").
+			Label("example.com/not-a-member", "").Obj(),
+	}
+	fh := newTestHandle(t, nil, pods)
+	cs := &CustomScheduler{handle: fh, scoreMode: leastMode, excludeLabel: "example.com/not-a-member"}
+
+	_, got := cs.PreFilter(context.Background(), nil, pods[0])
+	want := framework.NewStatus(framework.Unschedulable, "Not enough pods in group g1, 2 present, minimum required is 3")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCustomScheduler_PreFilterGroupByOwner(t *testing.T) {
+	job := func(name, uid string) *metav1.OwnerReference {
+		controller := true
+		return &metav1.OwnerReference{APIVersion: "batch/v1", Kind: "Job", Name: name, UID: types.UID(uid), Controller: &controller}
+	}
+	member := func(name string, owner *metav1.OwnerReference) *v1.Pod {
+		p := st.MakePod().Name(name).Namespace("default").Label("minAvailable", "3").Obj()
+		if owner != nil {
+			p.OwnerReferences = []metav1.OwnerReference{*owner}
+		}
+		return p
+	}
+	train, eval := job("train", "train-uid"), job("eval", "eval-uid")
+
+	tests := []struct {
+		name string
+		pods []*v1.Pod
+		want *framework.Status
+	}{
+		{
+			name: "siblings reach minAvailable",
+			pods: []*v1.Pod{member("train-0", train), member("train-1", train), member("train-2", train), member("eval-0", eval)},
+			want: framework.NewStatus(framework.Success, ""),
+		},
+		{
+			name: "pods of another owner don't count",
+			pods: []*v1.Pod{member("train-0", train), member("train-1", train), member("eval-0", eval), member("eval-1", eval)},
+			want: framework.NewStatus(framework.Unschedulable, "Not enough pods in group default/Job/train, 2 present, minimum required is 3"),
+		},
+		{
+			name: "pod without controller",
+			pods: []*v1.Pod{member("bare", nil)},
+			want: framework.NewStatus(framework.Success, ""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, nil, tt.pods)
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode, groupByOwner: true, skipUngrouped: true}
+			_, got := cs.PreFilter(context.Background(), nil, tt.pods[0])
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterNormalizesGroupValues(t *testing.T) {
+	member := func(name, group string) *v1.Pod {
+		return st.MakePod().Name(name).Namespace("default").Label("podGroup", group).Label("minAvailable", "3").Obj()
+	}
+
+	tests := []struct {
+		name            string
+		caseInsensitive bool
+		pods            []*v1.Pod
+		want            *framework.Status
+	}{
+		{
+			name: "whitespace variants share a group",
+			pods: []*v1.Pod{member("pod0", "worker"), member("pod1", "worker "), member("pod2", " worker")},
+			want: framework.NewStatus(framework.Success, ""),
+		},
+		{
+			name: "case variants are separate groups by default",
+			pods: []*v1.Pod{member("pod0", "Worker"), member("pod1", "worker"), member("pod2", "WORKER")},
+			want: framework.NewStatus(framework.Unschedulable, "Not enough pods in group Worker, 1 present, minimum required is 3"),
+		},
+		{
+			name:            "case variants share a group when case-insensitive",
+			caseInsensitive: true,
+			pods:            []*v1.Pod{member("pod0", "Worker"), member("pod1", "worker "), member("pod2", "WORKER")},
+			want:            framework.NewStatus(framework.Success, ""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, nil, tt.pods)
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode, caseInsensitiveGroups: tt.caseInsensitive}
+			_, got := cs.PreFilter(context.Background(), nil, tt.pods[0])
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterGangGatingDisabled(t *testing.T) {
+	pods := []*v1.Pod{
+		st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "web").Label("minAvailable", "3").Obj(),
+		st.MakePod().Name("pod1").Namespace("default").Label("podGroup", "web").Label("minAvailable", "3").Obj(),
+	}
+	fh := newTestHandle(t, nil, pods)
+
+	tests := []struct {
+		name           string
+		skipGangGating bool
+		want           *framework.Status
+	}{
+		{
+			name: "under-quota group is held with gating",
+			want: framework.NewStatus(framework.Unschedulable, "Not enough pods in group web, 2 present, minimum required is 3"),
+		},
+		{
+			name:           "under-quota group schedules without gating",
+			skipGangGating: true,
+			want:           framework.NewStatus(framework.Success, ""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode, skipGangGating: tt.skipGangGating}
+			_, got := cs.PreFilter(context.Background(), nil, pods[0])
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterGangSizeMetric(t *testing.T) {
+	registerMetrics()
+	member := func(name, group, minAvailable string) *v1.Pod {
+		return st.MakePod().Name(name).Namespace("default").Label("podGroup", group).Label("minAvailable", minAvailable).Obj()
+	}
+	pods := []*v1.Pod{
+		member("short0", "short", "4"),
+		member("short1", "short", "4"),
+		member("ready0", "ready", "1"),
+	}
+	fh := newTestHandle(t, nil, pods)
+	cs := &CustomScheduler{handle: fh, scoreMode: leastMode}
+
+	tests := []struct {
+		pod    *v1.Pod
+		result string
+		ratio  float64
+	}{
+		{pod: pods[0], result: gangRejected, ratio: 0.5},
+		{pod: pods[2], result: gangPassed, ratio: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.result, func(t *testing.T) {
+			histogram := gangSizeRatio.WithLabelValues(tt.result)
+			beforeCount, err := testutil.GetHistogramMetricCount(histogram)
+			if err != nil {
+				t.Fatalf("fail to read metric: %s", err)
+			}
+			beforeSum, err := testutil.GetHistogramMetricValue(histogram)
+			if err != nil {
+				t.Fatalf("fail to read metric: %s", err)
+			}
+
+			cs.PreFilter(context.Background(), nil, tt.pod)
+
+			afterCount, err := testutil.GetHistogramMetricCount(histogram)
+			if err != nil {
+				t.Fatalf("fail to read metric: %s", err)
+			}
+			afterSum, err := testutil.GetHistogramMetricValue(histogram)
+			if err != nil {
+				t.Fatalf("fail to read metric: %s", err)
+			}
+			if afterCount-beforeCount != 1 {
+				t.Errorf("expected one %s observation, got %d", tt.result, afterCount-beforeCount)
+			}
+			if got := afterSum - beforeSum; got != tt.ratio {
+				t.Errorf("expected %s ratio %v, got %v", tt.result, tt.ratio, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_PreFilterWarmingUp(t *testing.T) {
+	fh := warmingUpHandle{Handle: newTestHandle(t, nil, nil)}
+	cs := &CustomScheduler{handle: fh, scoreMode: leastMode}
+	pod := st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "1").Obj()
+
+	_, got := cs.PreFilter(context.Background(), nil, pod)
+	want := framework.NewStatus(framework.Unschedulable, "informer factory unavailable, group members can't be listed yet")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCustomScheduler_PreFilterMinAvailableByPriority(t *testing.T) {
+	member := func(name string, priority int32) *v1.Pod {
+		return st.MakePod().Name(name).Namespace("default").Label("podGroup", "g1").Label("minAvailable", "3").Priority(priority).Obj()
+	}
+	high := member("high0", 100)
+	pods := []*v1.Pod{
+		high,
+		member("high1", 200),
+		member("low0", 10),
+		// no priority counts as 0
+		st.MakePod().Name("none").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "3").Obj(),
+	}
+	fh := newTestHandle(t, nil, pods)
+
+	tests := []struct {
+		name       string
+		byPriority bool
+		pod        *v1.Pod
+		want       *framework.Status
+	}{
+		{name: "every member counts", pod: high, want: framework.NewStatus(framework.Success, "")},
+		{name: "lower priorities don't count", byPriority: true, pod: high, want: framework.NewStatus(framework.Unschedulable, "Not enough pods in group g1, 2 present, minimum required is 3")},
+		{name: "a low-priority pod counts the higher ones", byPriority: true, pod: pods[2], want: framework.NewStatus(framework.Success, "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: leastMode, minAvailableByPriority: tt.byPriority}
+			_, got := cs.PreFilter(context.Background(), nil, tt.pod)
+			if got.Code() != tt.want.Code() || got.Message() != tt.want.Message() {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
 func TestCustomScheduler_Score(t *testing.T) {
 	type TestScoreInput struct {
 		ctx       context.Context
@@ -151,81 +1387,1546 @@ func TestCustomScheduler_Score(t *testing.T) {
 		nodeNames []string
 	}
 	tests := []struct {
-		name      string
-		nodeInfos []*framework.NodeInfo
-		mode      string
-		args      TestScoreInput
-		want      string
+		name      string
+		nodeInfos []*framework.NodeInfo
+		mode      ScoreMode
+		args      TestScoreInput
+		want      string
+	}{
+		{
+			name:      "least mode",
+			nodeInfos: []*framework.NodeInfo{makeNodeInfo("m1", 1000, 100), makeNodeInfo("m2", 1000, 200)},
+			mode:      "Least",
+			args: TestScoreInput{
+				ctx:       context.Background(),
+				state:     nil,
+				pod:       &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}},
+				nodeNames: []string{"m1", "m2"},
+			},
+			want: "m1",
+		},
+		{
+			name:      "most mode",
+			nodeInfos: []*framework.NodeInfo{makeNodeInfo("m1", 1000, 100), makeNodeInfo("m2", 1000, 200)},
+			mode:      "Most",
+			args: TestScoreInput{
+				ctx:       context.Background(),
+				state:     nil,
+				pod:       &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}},
+				nodeNames: []string{"m1", "m2"},
+			},
+			want: "m2",
+		},
+		{
+			name: "bin pack mode",
+			nodeInfos: []*framework.NodeInfo{
+				makeNodeInfo("m1", 1000, 200),
+				makeNodeInfoWithPods("m2", 1000, 200, makePodWithMemory("p1", 100)),
+				makeNodeInfo("m3", 1000, 0),
+			},
+			mode: "BinPack",
+			args: TestScoreInput{
+				ctx:       context.Background(),
+				state:     nil,
+				pod:       &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}},
+				nodeNames: []string{"m1", "m2", "m3"},
+			},
+			want: "m2",
+		},
+		{
+			name: "least ephemeral storage mode",
+			nodeInfos: []*framework.NodeInfo{
+				makeNodeInfoWithEphemeralStorage("m1", 1000, 200, 100),
+				makeNodeInfoWithEphemeralStorage("m2", 1000, 100, 200),
+			},
+			mode: "LeastEphemeralStorage",
+			args: TestScoreInput{
+				ctx:       context.Background(),
+				state:     nil,
+				pod:       &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}},
+				nodeNames: []string{"m1", "m2"},
+			},
+			want: "m1",
+		},
+		{
+			name: "most ephemeral storage mode",
+			nodeInfos: []*framework.NodeInfo{
+				makeNodeInfoWithEphemeralStorage("m1", 1000, 200, 100),
+				makeNodeInfoWithEphemeralStorage("m2", 1000, 100, 200),
+			},
+			mode: "MostEphemeralStorage",
+			args: TestScoreInput{
+				ctx:       context.Background(),
+				state:     nil,
+				pod:       &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}},
+				nodeNames: []string{"m1", "m2"},
+			},
+			want: "m2",
+		},
+		{
+			name: "node without ephemeral storage scores as zero",
+			nodeInfos: []*framework.NodeInfo{
+				makeNodeInfo("m1", 1000, 200),
+				makeNodeInfoWithEphemeralStorage("m2", 1000, 100, 200),
+			},
+			mode: "LeastEphemeralStorage",
+			args: TestScoreInput{
+				ctx:       context.Background(),
+				state:     nil,
+				pod:       &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}},
+				nodeNames: []string{"m1", "m2"},
+			},
+			want: "m1",
+		},
+		{
+			name:      "pod label overrides least mode with most",
+			nodeInfos: []*framework.NodeInfo{makeNodeInfo("m1", 1000, 100), makeNodeInfo("m2", 1000, 200)},
+			mode:      "Least",
+			args: TestScoreInput{
+				ctx:   context.Background(),
+				state: nil,
+				pod: &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"scoreMode": "Most"}},
+					Spec:       v1.PodSpec{Containers: []v1.Container{}},
+				},
+				nodeNames: []string{"m1", "m2"},
+			},
+			want: "m2",
+		},
+		{
+			name:      "invalid pod label falls back to configured mode",
+			nodeInfos: []*framework.NodeInfo{makeNodeInfo("m1", 1000, 100), makeNodeInfo("m2", 1000, 200)},
+			mode:      "Least",
+			args: TestScoreInput{
+				ctx:   context.Background(),
+				state: nil,
+				pod: &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"scoreMode": "Random"}},
+					Spec:       v1.PodSpec{Containers: []v1.Container{}},
+				},
+				nodeNames: []string{"m1", "m2"},
+			},
+			want: "m1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(client, 0)
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+				st.RegisterPluginAsExtensions(Name, New, "Score"),
+			}
+			fakeSharedLister := &fakeSharedLister{nodes: tt.nodeInfos}
+
+			fh, err := st.NewFramework(
+				registeredPlugins,
+				"default-scheduler",
+				wait.NeverStop,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithInformerFactory(informerFactory),
+				frameworkruntime.WithSnapshotSharedLister(fakeSharedLister),
+			)
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			cs := &CustomScheduler{
+				handle:    fh,
+				scoreMode: tt.mode,
+			}
+
+			highest := int64(math.MinInt64)
+			bestNode := ""
+			for _, nodeName := range tt.args.nodeNames {
+				got, status := cs.Score(tt.args.ctx, tt.args.state, tt.args.pod, nodeName)
+				if !status.IsSuccess() {
+					t.Errorf("unexpected error: %v", status)
+				}
+
+				if got > highest {
+					highest = got
+					bestNode = nodeName
+				}
+			}
+
+			if bestNode != tt.want {
+				t.Errorf("bestNode is = %v, want %v", bestNode, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryScore(t *testing.T) {
+	for _, memory := range []int64{0, 1, 3 << 30, 1<<40 + 7} {
+		ni := makeNodeInfo("m1", 1000, memory)
+		if got := memoryScore(ni); got != ni.Allocatable.Memory {
+			t.Errorf("expected %d bytes, got %d", ni.Allocatable.Memory, got)
+		}
+	}
+}
+
+func TestCustomScheduler_ScoreByPodCapacity(t *testing.T) {
+	// m1 is big but mostly used, m2 is small but empty
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfoWithPods("m1", 1000, 1000, makePodWithMemory("p1", 900)),
+		makeNodeInfo("m2", 1000, 500),
+	}
+	tests := []struct {
+		name    string
+		scoreBy string
+		pod     *v1.Pod
+		want    string
+	}{
+		{name: "raw memory prefers the big node", scoreBy: memoryScoreBy, pod: makePodWithMemory("incoming", 100), want: "m1"},
+		{name: "pod capacity prefers the node fitting more copies", scoreBy: podCapacityScoreBy, pod: makePodWithMemory("incoming", 100), want: "m2"},
+		{name: "zero request counts free bytes", scoreBy: podCapacityScoreBy, pod: &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{}}}}, want: "m2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(client, 0)
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			}
+			fh, err := st.NewFramework(
+				registeredPlugins,
+				"default-scheduler",
+				wait.NeverStop,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithInformerFactory(informerFactory),
+				frameworkruntime.WithSnapshotSharedLister(&fakeSharedLister{nodes: nodeInfos}),
+			)
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			cs := &CustomScheduler{
+				handle:    fh,
+				scoreMode: mostMode,
+				scoreBy:   tt.scoreBy,
+			}
+
+			highest := int64(math.MinInt64)
+			bestNode := ""
+			for _, ni := range nodeInfos {
+				got, status := cs.Score(context.Background(), nil, tt.pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected error: %v", status)
+				}
+				if got > highest {
+					highest = got
+					bestNode = ni.Node().Name
+				}
+			}
+			if bestNode != tt.want {
+				t.Errorf("bestNode is = %v, want %v", bestNode, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScoreCurve(t *testing.T) {
+	const gi = int64(1) << 30
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("small", 1000, 1*gi),
+		makeNodeInfo("medium", 1000, 1*gi+gi/2),
+		makeNodeInfo("huge", 1000, 64*gi),
+	}
+	tests := []struct {
+		name  string
+		curve string
+		// check receives the raw scores of small, medium and huge in order.
+		check func(t *testing.T, small, medium, huge int64)
+	}{
+		{
+			name:  "linear keeps strict ordering",
+			curve: linearCurve,
+			check: func(t *testing.T, small, medium, huge int64) {
+				if !(small < medium && medium < huge) {
+					t.Errorf("expected small < medium < huge, got %d, %d, %d", small, medium, huge)
+				}
+			},
+		},
+		{
+			name:  "log keeps ordering but compresses huge nodes",
+			curve: logCurve,
+			check: func(t *testing.T, small, medium, huge int64) {
+				if !(small < medium && medium < huge) {
+					t.Errorf("expected small < medium < huge, got %d, %d, %d", small, medium, huge)
+				}
+				if float64(huge)/float64(small) >= 2 {
+					t.Errorf("expected log curve to compress the huge node, got ratio %f", float64(huge)/float64(small))
+				}
+			},
+		},
+		{
+			name:  "step buckets close nodes into the same tier",
+			curve: stepCurve,
+			check: func(t *testing.T, small, medium, huge int64) {
+				if small != medium {
+					t.Errorf("expected small and medium in the same tier, got %d and %d", small, medium)
+				}
+				if medium >= huge {
+					t.Errorf("expected huge in a higher tier, got %d and %d", medium, huge)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(client, 0)
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			}
+			fh, err := st.NewFramework(
+				registeredPlugins,
+				"default-scheduler",
+				wait.NeverStop,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithInformerFactory(informerFactory),
+				frameworkruntime.WithSnapshotSharedLister(&fakeSharedLister{nodes: nodeInfos}),
+			)
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			cs := &CustomScheduler{
+				handle:     fh,
+				scoreMode:  mostMode,
+				scoreCurve: tt.curve,
+			}
+
+			pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+			scores := make([]int64, 0, len(nodeInfos))
+			for _, ni := range nodeInfos {
+				got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected error: %v", status)
+				}
+				scores = append(scores, got)
+			}
+			tt.check(t, scores[0], scores[1], scores[2])
+		})
+	}
+}
+
+func TestCustomScheduler_ScoreStableTiebreak(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 200),
+		makeNodeInfo("m2", 1000, 200),
+		makeNodeInfo("m3", 1000, 100),
+	}
+	client := clientsetfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	registeredPlugins := []st.RegisterPluginFunc{
+		st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}
+	fh, err := st.NewFramework(
+		registeredPlugins,
+		"default-scheduler",
+		wait.NeverStop,
+		frameworkruntime.WithClientSet(client),
+		frameworkruntime.WithInformerFactory(informerFactory),
+		frameworkruntime.WithSnapshotSharedLister(&fakeSharedLister{nodes: nodeInfos}),
+	)
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+
+	cs := &CustomScheduler{
+		handle:         fh,
+		scoreMode:      mostMode,
+		scoreCurve:     linearCurve,
+		stableTiebreak: true,
+	}
+
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+	var first framework.NodeScoreList
+	for round := 0; round < 5; round++ {
+		scores := framework.NodeScoreList{}
+		for _, ni := range nodeInfos {
+			got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+			if !status.IsSuccess() {
+				t.Fatalf("unexpected error: %v", status)
+			}
+			scores = append(scores, framework.NodeScore{Name: ni.Node().Name, Score: got})
+		}
+		if status := cs.NormalizeScore(context.Background(), nil, pod, scores); !status.IsSuccess() {
+			t.Fatalf("unexpected error: %v", status)
+		}
+
+		if scores[0].Score == scores[1].Score {
+			t.Fatalf("expected equal-memory nodes to be ordered, both got %d", scores[0].Score)
+		}
+		if scores[2].Score >= scores[0].Score || scores[2].Score >= scores[1].Score {
+			t.Errorf("expected the smaller node to rank last, got %v", scores)
+		}
+		if first == nil {
+			first = scores
+			continue
+		}
+		if !reflect.DeepEqual(first, scores) {
+			t.Errorf("round %d: expected %v, got %v", round, first, scores)
+		}
+	}
+}
+
+func TestCustomScheduler_ScorePreferredNodeLabel(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 200),
+		makeNodeInfoWithLabels("m2", 1000, 200, map[string]string{"workload-tier": "batch"}),
+		makeNodeInfoWithLabels("m3", 1000, 200, map[string]string{"workload-tier": "serving"}),
+	}
+	client := clientsetfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	registeredPlugins := []st.RegisterPluginFunc{
+		st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}
+	fh, err := st.NewFramework(
+		registeredPlugins,
+		"default-scheduler",
+		wait.NeverStop,
+		frameworkruntime.WithClientSet(client),
+		frameworkruntime.WithInformerFactory(informerFactory),
+		frameworkruntime.WithSnapshotSharedLister(&fakeSharedLister{nodes: nodeInfos}),
+	)
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+
+	cs := &CustomScheduler{
+		handle:              fh,
+		scoreMode:           leastMode,
+		preferredLabelKey:   "workload-tier",
+		preferredLabelValue: "batch",
+		preferredBonus:      50,
+	}
+
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+	scores := map[string]int64{}
+	for _, ni := range nodeInfos {
+		got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+		if !status.IsSuccess() {
+			t.Fatalf("unexpected error: %v", status)
+		}
+		scores[ni.Node().Name] = got
+	}
+	if scores["m2"] != scores["m1"]+50 {
+		t.Errorf("expected labeled node to get the bonus, got %v", scores)
+	}
+	if scores["m3"] != scores["m1"] {
+		t.Errorf("expected a different label value to get no bonus, got %v", scores)
+	}
+}
+
+func TestCustomScheduler_ScoreNeutral(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfoWithLabels("m2", 1000, 200, map[string]string{"workload-tier": "batch"}),
+	}
+	client := clientsetfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	registeredPlugins := []st.RegisterPluginFunc{
+		st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}
+	fh, err := st.NewFramework(
+		registeredPlugins,
+		"default-scheduler",
+		wait.NeverStop,
+		frameworkruntime.WithClientSet(client),
+		frameworkruntime.WithInformerFactory(informerFactory),
+		frameworkruntime.WithSnapshotSharedLister(&fakeSharedLister{nodes: nodeInfos}),
+	)
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+
+	cs := &CustomScheduler{
+		handle:              fh,
+		scoreMode:           neutralMode,
+		stableTiebreak:      true,
+		preferredLabelKey:   "workload-tier",
+		preferredLabelValue: "batch",
+		preferredBonus:      50,
+	}
+
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+	scores := framework.NodeScoreList{}
+	for _, ni := range nodeInfos {
+		got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+		if !status.IsSuccess() {
+			t.Fatalf("unexpected error: %v", status)
+		}
+		scores = append(scores, framework.NodeScore{Name: ni.Node().Name, Score: got})
+	}
+	if status := cs.NormalizeScore(context.Background(), nil, pod, scores); !status.IsSuccess() {
+		t.Fatalf("unexpected error: %v", status)
+	}
+	for _, s := range scores {
+		if s.Score != 0 {
+			t.Errorf("expected zero score for node %s, got %d", s.Name, s.Score)
+		}
+	}
+}
+
+func TestCustomScheduler_ScoreBlend(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 200),
+	}
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	tests := []struct {
+		name      string
+		packRatio float64
+		// want is the mode the blend should score like.
+		want ScoreMode
+	}{
+		{name: "ratio 0 spreads like Least", packRatio: 0, want: leastMode},
+		{name: "ratio 1 packs like Most", packRatio: 1, want: mostMode},
+		{name: "ratio 0.5 is neutral", packRatio: 0.5, want: neutralMode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, nodeInfos, nil)
+			blend := &CustomScheduler{handle: fh, scoreMode: blendMode, packRatio: tt.packRatio}
+			ref := &CustomScheduler{handle: fh, scoreMode: tt.want}
+			for _, ni := range nodeInfos {
+				got, status := blend.Score(context.Background(), nil, pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected error: %v", status)
+				}
+				want, _ := ref.Score(context.Background(), nil, pod, ni.Node().Name)
+				if got != want {
+					t.Errorf("expected score %d on node %s, got %d", want, ni.Node().Name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScoreCost(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfoWithLabels("spot", 1000, 100, map[string]string{"node.example.com/cost": "0.5"}),
+		makeNodeInfoWithLabels("on-demand", 1000, 100, map[string]string{"node.example.com/cost": "2"}),
+		makeNodeInfoWithLabels("unpriced", 1000, 100, nil),
+		makeNodeInfoWithLabels("mispriced", 1000, 100, map[string]string{"node.example.com/cost": "cheap"}),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode, costLabel: "node.example.com/cost", costWeight: 10}
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	want := map[string]int64{"spot": 95, "on-demand": 80, "unpriced": 100, "mispriced": 100}
+	for _, ni := range nodeInfos {
+		got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+		if !status.IsSuccess() {
+			t.Fatalf("unexpected error: %v", status)
+		}
+		if got != want[ni.Node().Name] {
+			t.Errorf("expected score %d on node %s, got %d", want[ni.Node().Name], ni.Node().Name, got)
+		}
+	}
+}
+
+func TestCustomScheduler_ScoreComponentCaps(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfoWithLabels("huge", 1000, 1<<40, map[string]string{"node.example.com/cost": "2", "zone": "a"}),
+		makeNodeInfoWithLabels("small", 1000, 100, map[string]string{"node.example.com/cost": "0.5"}),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	tests := []struct {
+		name       string
+		caps       map[string]int64
+		scoreClamp int64
+		want       map[string]int64
+	}{
+		{
+			name: "uncapped",
+			want: map[string]int64{"huge": 1<<40 - 20 + 50, "small": 95},
+		},
+		{
+			name: "resource capped",
+			caps: map[string]int64{resourceComponent: 100},
+			want: map[string]int64{"huge": 100 - 20 + 50, "small": 95},
+		},
+		{
+			name: "every component capped",
+			caps: map[string]int64{resourceComponent: 100, costComponent: 10, affinityComponent: 5},
+			want: map[string]int64{"huge": 100 - 10 + 5, "small": 95},
+		},
+		{
+			name:       "clamped",
+			caps:       map[string]int64{resourceComponent: 100},
+			scoreClamp: 110,
+			want:       map[string]int64{"huge": 110, "small": 95},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{
+				handle:              fh,
+				scoreMode:           mostMode,
+				costLabel:           "node.example.com/cost",
+				costWeight:          10,
+				preferredLabelKey:   "zone",
+				preferredLabelValue: "a",
+				preferredBonus:      50,
+				componentCaps:       tt.caps,
+				scoreClamp:          tt.scoreClamp,
+			}
+			for _, ni := range nodeInfos {
+				got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected error: %v", status)
+				}
+				if got != tt.want[ni.Node().Name] {
+					t.Errorf("expected score %d on node %s, got %d", tt.want[ni.Node().Name], ni.Node().Name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestNew_MaxRawScore(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("huge", 1000, 1<<40),
+		makeNodeInfo("small", 1000, 100),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	p, err := New(&runtime.Unknown{Raw: []byte(`{"mode": "Most", "maxRawScore": 1000}`)}, fh)
+	if err != nil {
+		t.Fatalf("fail to create plugin: %s", err)
+	}
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	want := map[string]int64{"huge": 1000, "small": 100}
+	for _, ni := range nodeInfos {
+		got, status := p.(*CustomScheduler).Score(context.Background(), nil, pod, ni.Node().Name)
+		if !status.IsSuccess() {
+			t.Fatalf("unexpected error: %v", status)
+		}
+		if got != want[ni.Node().Name] {
+			t.Errorf("expected score %d on node %s, got %d", want[ni.Node().Name], ni.Node().Name, got)
+		}
+	}
+}
+
+func TestCustomScheduler_ScoreWarmingUp(t *testing.T) {
+	fh := warmingUpHandle{Handle: newTestHandle(t, []*framework.NodeInfo{makeNodeInfo("m1", 1000, 100)}, nil)}
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode}
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	got, status := cs.Score(context.Background(), nil, pod, "m1")
+	if !status.IsSuccess() {
+		t.Fatalf("unexpected error: %v", status)
+	}
+	if got != 0 {
+		t.Errorf("expected a neutral score without a snapshot, got %d", got)
+	}
+}
+
+func TestCustomScheduler_ScoreColdNodes(t *testing.T) {
+	now := time.Now()
+	readySince := func(name string, memory int64, since time.Time) *framework.NodeInfo {
+		ni := makeNodeInfo(name, 1000, memory)
+		ni.Node().Status.Conditions = []v1.NodeCondition{
+			{Type: v1.NodeReady, Status: v1.ConditionTrue, LastTransitionTime: metav1.NewTime(since)},
+		}
+		return ni
+	}
+	nodeInfos := []*framework.NodeInfo{
+		readySince("warm", 100, now.Add(-time.Hour)),
+		readySince("cold", 120, now.Add(-time.Minute)),
+		makeNodeInfo("unreported", 100, 100),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	tests := []struct {
+		mode ScoreMode
+		want map[string]int64
+	}{
+		{mode: mostMode, want: map[string]int64{"warm": 100, "cold": 60, "unreported": 100}},
+		{mode: leastMode, want: map[string]int64{"warm": -100, "cold": -180, "unreported": -100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			cs := &CustomScheduler{
+				handle:                 fh,
+				scoreMode:              tt.mode,
+				coldNodeWindow:         10 * time.Minute,
+				coldNodePenaltyPercent: 50,
+				clock:                  testingclock.NewFakePassiveClock(now),
+			}
+			for _, ni := range nodeInfos {
+				got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected error: %v", status)
+				}
+				if got != tt.want[ni.Node().Name] {
+					t.Errorf("expected score %d on node %s, got %d", tt.want[ni.Node().Name], ni.Node().Name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScorePressure(t *testing.T) {
+	withCondition := func(name string, memory int64, cond v1.NodeConditionType) *framework.NodeInfo {
+		ni := makeNodeInfo(name, 1000, memory)
+		ni.Node().Status.Conditions = []v1.NodeCondition{{Type: cond, Status: v1.ConditionTrue}}
+		return ni
+	}
+	nodeInfos := []*framework.NodeInfo{
+		withCondition("healthy", 100, v1.NodeReady),
+		withCondition("memory-pressure", 150, v1.NodeMemoryPressure),
+		withCondition("disk-pressure", 100, v1.NodeDiskPressure),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	tests := []struct {
+		mode ScoreMode
+		want map[string]int64
+	}{
+		{mode: mostMode, want: map[string]int64{"healthy": 100, "memory-pressure": 75, "disk-pressure": 50}},
+		{mode: leastMode, want: map[string]int64{"healthy": -100, "memory-pressure": -225, "disk-pressure": -150}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: tt.mode, pressurePenaltyPercent: 50}
+			for _, ni := range nodeInfos {
+				got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected error: %v", status)
+				}
+				if got != tt.want[ni.Node().Name] {
+					t.Errorf("expected score %d on node %s, got %d", tt.want[ni.Node().Name], ni.Node().Name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScorePriorityWeighted(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 100),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode, priorityWeighted: true}
+
+	tests := []struct {
+		name     string
+		priority *int32
+		want     int64
+	}{
+		{name: "no priority", priority: nil, want: 100},
+		{name: "negative priority", priority: pointer.Int32(-10), want: 100},
+		{name: "low priority", priority: pointer.Int32(100000000), want: 110},
+		{name: "high priority", priority: pointer.Int32(1000000000), want: 200},
+		{name: "system priority", priority: pointer.Int32(2000001000), want: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := st.MakePod().Name("pod0").Obj()
+			pod.Spec.Priority = tt.priority
+			for _, ni := range nodeInfos {
+				got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected error: %v", status)
+				}
+				if got != tt.want {
+					t.Errorf("expected score %d on node %s, got %d", tt.want, ni.Node().Name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScorePodCountAware(t *testing.T) {
+	var pods []*v1.Pod
+	for i := 0; i < 55; i++ {
+		pods = append(pods, st.MakePod().Name(fmt.Sprintf("small%d", i)).Obj())
+	}
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("quiet", 1000, 100),
+		makeNodeInfoWithPods("crowded", 1000, 100, pods...),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	tests := []struct {
+		name          string
+		podCountAware bool
+		want          map[string]int64
+	}{
+		{name: "memory only", want: map[string]int64{"quiet": 100, "crowded": 100}},
+		// half of the default 110 pod slots are taken
+		{name: "pod count aware", podCountAware: true, want: map[string]int64{"quiet": 100, "crowded": 50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: mostMode, podCountAware: tt.podCountAware}
+			for _, ni := range nodeInfos {
+				got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected error: %v", status)
+				}
+				if got != tt.want[ni.Node().Name] {
+					t.Errorf("expected score %d on node %s, got %d", tt.want[ni.Node().Name], ni.Node().Name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScoreNilAllocatable(t *testing.T) {
+	registering := makeNodeInfo("registering", 0, 0)
+	registering.Allocatable = nil
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 200),
+		registering,
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	for _, mode := range []ScoreMode{leastMode, mostMode, binPackMode} {
+		t.Run(mode.String(), func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: mode, stableTiebreak: true}
+			for _, s := range scoreNodes(t, cs, nil, pod, nodeInfos) {
+				if s.Name == "registering" && s.Score != framework.MinNodeScore {
+					t.Errorf("expected node without allocatable to score %d, got %d", framework.MinNodeScore, s.Score)
+				}
+				if s.Score < framework.MinNodeScore || s.Score > framework.MaxNodeScore {
+					t.Errorf("expected score of node %s within range, got %d", s.Name, s.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScorePodHeadroom(t *testing.T) {
+	nearCap := makeNodeInfoWithPods("near-cap", 1000, 1000, makePodWithMemory("p0", 0), makePodWithMemory("p1", 0), makePodWithMemory("p2", 0))
+	nearCap.Allocatable.AllowedPodNumber = 4
+	overCap := makeNodeInfoWithPods("over-cap", 1000, 1000, makePodWithMemory("p3", 0), makePodWithMemory("p4", 0))
+	overCap.Allocatable.AllowedPodNumber = 1
+	roomy := makeNodeInfoWithPods("roomy", 1000, 1000, makePodWithMemory("p5", 0))
+	roomy.Allocatable.AllowedPodNumber = 110
+	nodeInfos := []*framework.NodeInfo{nearCap, overCap, roomy}
+	fh := newTestHandle(t, nodeInfos, nil)
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	tests := []struct {
+		mode ScoreMode
+		want map[string]int64
+	}{
+		{mode: mostPodHeadroomMode, want: map[string]int64{"near-cap": 1, "over-cap": 0, "roomy": 109}},
+		{mode: leastPodHeadroomMode, want: map[string]int64{"near-cap": -1, "over-cap": 0, "roomy": -109}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: tt.mode}
+			for _, ni := range nodeInfos {
+				got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected error: %v", status)
+				}
+				if got != tt.want[ni.Node().Name] {
+					t.Errorf("expected score %d on node %s, got %d", tt.want[ni.Node().Name], ni.Node().Name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_Deterministic(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfoWithLabels("m1", 1000, 100, map[string]string{"zone": "a", "tier": "batch"}),
+		makeNodeInfoWithLabels("m2", 1000, 100, map[string]string{"zone": "a"}),
+		makeNodeInfoWithLabels("m3", 1000, 300, map[string]string{"zone": "b", "tier": "batch"}),
+	}
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	pods := []*v1.Pod{
+		st.MakePod().Name("pod0").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "2").
+			CreationTimestamp(metav1.NewTime(created)).Obj(),
+	}
+	fh := newTestHandle(t, nodeInfos, pods)
+	fakeClock := testingclock.NewFakePassiveClock(created.Add(30 * time.Second))
+
+	run := func() (*framework.Status, framework.NodeScoreList) {
+		cs := &CustomScheduler{
+			handle:                    fh,
+			clock:                     fakeClock,
+			scoreMode:                 mostMode,
+			stableTiebreak:            true,
+			hardFailAfter:             time.Minute,
+			preferredNodeLabels:       map[string]string{"zone": "a", "tier": "batch"},
+			preferredNodeLabelsWeight: 10,
+		}
+		_, status := cs.PreFilter(context.Background(), nil, pods[0])
+		return status, scoreNodes(t, cs, nil, pods[0], nodeInfos)
+	}
+
+	firstStatus, first := run()
+	secondStatus, second := run()
+	if !reflect.DeepEqual(firstStatus, secondStatus) {
+		t.Errorf("expected identical PreFilter results, got %v and %v", firstStatus, secondStatus)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected identical scores, got %v and %v", first, second)
+	}
+
+	// the deadline follows the injected clock rather than the wall clock
+	if firstStatus.Code() != framework.Unschedulable {
+		t.Errorf("expected a retriable rejection before the deadline, got %v", firstStatus)
+	}
+	fakeClock.SetTime(created.Add(2 * time.Minute))
+	if status, _ := run(); status.Code() != framework.UnschedulableAndUnresolvable {
+		t.Errorf("expected the group to hard fail past the deadline, got %v", status)
+	}
+}
+
+func TestCustomScheduler_HasSynced(t *testing.T) {
+	fh := newTestHandle(t, nil, nil)
+	cs := &CustomScheduler{handle: fh}
+	if cs.HasSynced() {
+		t.Fatalf("expected an informer that was never started not to be synced")
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	fh.SharedInformerFactory().Start(stopCh)
+	fh.SharedInformerFactory().WaitForCacheSync(stopCh)
+	if !cs.HasSynced() {
+		t.Errorf("expected the plugin to be synced once its informer is")
+	}
+
+	if (&CustomScheduler{}).HasSynced() {
+		t.Errorf("expected a plugin without a handle not to be synced")
+	}
+}
+
+func TestCustomScheduler_ScoreBalanced(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		// the pod would use half the CPU and a tenth of the memory
+		makeNodeInfo("lopsided", 1000, 1000),
+		// the pod would use half of both
+		makeNodeInfo("proportional", 1000, 200),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	cs := &CustomScheduler{handle: fh, scoreMode: balancedMode}
+	pod := st.MakePod().Name("pod0").Req(map[v1.ResourceName]string{v1.ResourceCPU: "500m", v1.ResourceMemory: "100"}).Obj()
+
+	want := map[string]int64{"lopsided": 80, "proportional": 100}
+	for _, ni := range nodeInfos {
+		got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+		if !status.IsSuccess() {
+			t.Fatalf("unexpected error: %v", status)
+		}
+		if got != want[ni.Node().Name] {
+			t.Errorf("expected score %d on node %s, got %d", want[ni.Node().Name], ni.Node().Name, got)
+		}
+	}
+}
+
+func TestCustomScheduler_ScoreDominantResource(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		// the pod would use half the CPU and a tenth of the memory
+		makeNodeInfo("cpu-skewed", 1000, 1000),
+		// the pod would use a tenth of the CPU and most of the memory
+		makeNodeInfo("memory-skewed", 5000, 125),
+		// the pod would use a tenth of both
+		makeNodeInfo("roomy", 5000, 1000),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	pod := st.MakePod().Name("pod0").Req(map[v1.ResourceName]string{v1.ResourceCPU: "500m", v1.ResourceMemory: "100"}).Obj()
+
+	tests := []struct {
+		preference ScoreMode
+		want       map[string]int64
+	}{
+		{preference: "", want: map[string]int64{"cpu-skewed": 50, "memory-skewed": 20, "roomy": 90}},
+		{preference: mostMode, want: map[string]int64{"cpu-skewed": 50, "memory-skewed": 80, "roomy": 10}},
+	}
+	for _, tt := range tests {
+		t.Run("preference "+tt.preference.String(), func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: dominantMode, dominantPreference: tt.preference}
+			for _, ni := range nodeInfos {
+				got, status := cs.Score(context.Background(), nil, pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected error: %v", status)
+				}
+				if got != tt.want[ni.Node().Name] {
+					t.Errorf("expected score %d on node %s, got %d", tt.want[ni.Node().Name], ni.Node().Name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScoreNamespaceModes(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 200),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	cs := &CustomScheduler{
+		handle:         fh,
+		scoreMode:      leastMode,
+		namespaceModes: map[string]ScoreMode{"batch": mostMode, "web": leastMode},
+	}
+
+	tests := []struct {
+		namespace string
+		want      string
+	}{
+		{namespace: "batch", want: "m2"},
+		{namespace: "web", want: "m1"},
+		// namespaces without an override use the configured mode
+		{namespace: "default", want: "m1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			pod := st.MakePod().Name("pod0").Namespace(tt.namespace).Obj()
+			scores := scoreNodes(t, cs, nil, pod, nodeInfos)
+			best := scores[0]
+			for _, s := range scores[1:] {
+				if s.Score > best.Score {
+					best = s
+				}
+			}
+			if best.Name != tt.want {
+				t.Errorf("expected node %s to win for namespace %s, got %s", tt.want, tt.namespace, best.Name)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScoreAnnotation(t *testing.T) {
+	annotated := func(name, value string) *framework.NodeInfo {
+		ni := makeNodeInfo(name, 1000, 100)
+		ni.Node().Annotations = map[string]string{"example.com/cost-score": value}
+		return ni
+	}
+	nodeInfos := []*framework.NodeInfo{
+		annotated("cheap", "3"),
+		annotated("pricey", "7"),
+		annotated("garbled", "seven"),
+		makeNodeInfo("unannotated", 1000, 100),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	tests := []struct {
+		mode ScoreMode
+		want map[string]int64
+	}{
+		{mode: mostAnnotationMode, want: map[string]int64{"cheap": 0, "pricey": 100, "garbled": 0, "unannotated": 0}},
+		{mode: leastAnnotationMode, want: map[string]int64{"cheap": 100, "pricey": 0, "garbled": 0, "unannotated": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: tt.mode, annotationKey: "example.com/cost-score"}
+			for _, s := range scoreNodes(t, cs, nil, pod, nodeInfos) {
+				if s.Score != tt.want[s.Name] {
+					t.Errorf("expected node %s to score %d, got %d", s.Name, tt.want[s.Name], s.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScoreGPUMemory(t *testing.T) {
+	withGPUMemory := func(name string, resourceName v1.ResourceName, gpuMemory int64) *framework.NodeInfo {
+		ni := makeNodeInfo(name, 1000, 100)
+		ni.Allocatable.ScalarResources = map[v1.ResourceName]int64{resourceName: gpuMemory}
+		return ni
+	}
+	nodeInfos := []*framework.NodeInfo{
+		withGPUMemory("small-gpu", defaultGPUMemoryResource, 16<<30),
+		withGPUMemory("large-gpu", defaultGPUMemoryResource, 80<<30),
+		withGPUMemory("other-gpu", "example.com/vram", 40<<30),
+		makeNodeInfo("cpu-only", 1000, 100),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	tests := []struct {
+		name     string
+		mode     ScoreMode
+		resource v1.ResourceName
+		want     map[string]int64
 	}{
 		{
-			name:      "least mode",
-			nodeInfos: []*framework.NodeInfo{makeNodeInfo("m1", 1000, 100), makeNodeInfo("m2", 1000, 200)},
-			mode:      "Least",
-			args: TestScoreInput{
-				ctx:       context.Background(),
-				state:     nil,
-				pod:       &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}},
-				nodeNames: []string{"m1", "m2"},
-			},
-			want: "m1",
+			name: "most",
+			mode: mostGPUMemoryMode,
+			want: map[string]int64{"small-gpu": 0, "large-gpu": 100, "other-gpu": 0, "cpu-only": 0},
 		},
 		{
-			name:      "most mode",
-			nodeInfos: []*framework.NodeInfo{makeNodeInfo("m1", 1000, 100), makeNodeInfo("m2", 1000, 200)},
-			mode:      "Most",
-			args: TestScoreInput{
-				ctx:       context.Background(),
-				state:     nil,
-				pod:       &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}},
-				nodeNames: []string{"m1", "m2"},
-			},
-			want: "m2",
+			name: "least",
+			mode: leastGPUMemoryMode,
+			want: map[string]int64{"small-gpu": 100, "large-gpu": 0, "other-gpu": 0, "cpu-only": 0},
+		},
+		{
+			name:     "configured resource",
+			mode:     mostGPUMemoryMode,
+			resource: "example.com/vram",
+			want:     map[string]int64{"small-gpu": 0, "large-gpu": 0, "other-gpu": 100, "cpu-only": 0},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: tt.mode, gpuMemoryResource: tt.resource}
+			for _, s := range scoreNodes(t, cs, nil, pod, nodeInfos) {
+				if s.Score != tt.want[s.Name] {
+					t.Errorf("expected node %s to score %d, got %d", s.Name, tt.want[s.Name], s.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScoreGPUFit(t *testing.T) {
+	const gpu v1.ResourceName = "nvidia.com/gpu"
+	withGPUs := func(name string, gpus int64, pods ...*v1.Pod) *framework.NodeInfo {
+		ni := makeNodeInfoWithPods(name, 1000, 100, pods...)
+		ni.Allocatable.ScalarResources = map[v1.ResourceName]int64{gpu: gpus}
+		return ni
+	}
+	requesting := func(name string, gpus int64) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PodSpec{Containers: []v1.Container{{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{gpu: *resource.NewQuantity(gpus, resource.DecimalSI)},
+				},
+			}}},
+		}
+	}
+	nodeInfos := []*framework.NodeInfo{
+		withGPUs("free", 8),
+		// the biggest node, but most of its GPUs are taken
+		withGPUs("taken", 16, requesting("existing", 14)),
+		withGPUs("small", 6),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	pod := requesting("pod1", 4)
+
+	tests := []struct {
+		mode ScoreMode
+		want map[string]int64
+	}{
+		{mode: mostGPUMemoryMode, want: map[string]int64{"free": 100, "taken": 0, "small": 0}},
+		{mode: leastGPUMemoryMode, want: map[string]int64{"free": 0, "taken": 0, "small": 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: tt.mode, gpuMemoryResource: gpu}
+			for _, s := range scoreNodes(t, cs, nil, pod, nodeInfos) {
+				if s.Score != tt.want[s.Name] {
+					t.Errorf("expected node %s to score %d, got %d", s.Name, tt.want[s.Name], s.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScoreSimulatePlacement(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 1000),
+		// the bigger node is mostly taken already
+		makeNodeInfoWithPods("m2", 1000, 1500, makePodWithMemory("existing", 1000)),
+	}
+	pod := makePodWithMemory("pod1", 400)
+	tests := []struct {
+		name     string
+		simulate bool
+		want     map[string]int64
+	}{
+		{name: "allocatable", want: map[string]int64{"m1": 0, "m2": 100}},
+		{name: "simulate placement", simulate: true, want: map[string]int64{"m1": 100, "m2": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, nodeInfos, nil)
+			cs := &CustomScheduler{handle: fh, scoreMode: mostMode, scoreBy: memoryScoreBy, simulatePlacement: tt.simulate}
+			for _, s := range scoreNodes(t, cs, framework.NewCycleState(), pod, nodeInfos) {
+				if s.Score != tt.want[s.Name] {
+					t.Errorf("expected node %s to score %d, got %d", s.Name, tt.want[s.Name], s.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScoreFreeMemoryBasis(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 1000),
+		makeNodeInfoWithPods("m2", 1000, 1500, makePodWithMemory("existing", 1000)),
+		makeNodeInfo("m3", 1000, 750),
+	}
+	pod := makePodWithMemory("pod1", 100)
+	tests := []struct {
+		name  string
+		basis string
+		want  map[string]int64
+	}{
+		{name: "default", want: map[string]int64{"m1": 33, "m2": 100, "m3": 0}},
+		{name: "allocatable", basis: allocatableBasis, want: map[string]int64{"m1": 33, "m2": 100, "m3": 0}},
+		{name: "allocatable minus requested", basis: allocatableMinusRequestedBasis, want: map[string]int64{"m1": 100, "m2": 0, "m3": 50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := newTestHandle(t, nodeInfos, nil)
+			cs := &CustomScheduler{handle: fh, scoreMode: mostMode, scoreBy: memoryScoreBy, freeMemoryBasis: tt.basis}
+			for _, s := range scoreNodes(t, cs, framework.NewCycleState(), pod, nodeInfos) {
+				if s.Score != tt.want[s.Name] {
+					t.Errorf("expected node %s to score %d, got %d", s.Name, tt.want[s.Name], s.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestNew_NormalizeScores(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "default", args: `{"mode": "Least"}`, want: true},
+		{name: "enabled", args: `{"mode": "Least", "normalizeScores": true}`, want: true},
+		{name: "disabled", args: `{"mode": "BinPack", "normalizeScores": false}`},
+		{name: "disabled with raw memory scores", args: `{"mode": "Least", "normalizeScores": false}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(&runtime.Unknown{Raw: []byte(tt.args)}, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fail to create plugin: %s", err)
+			}
+			if got := p.(*CustomScheduler).ScoreExtensions() != nil; got != tt.want {
+				t.Errorf("expected ScoreExtensions set %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ModeForRawModeLabelWithoutNormalize(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	cs := &CustomScheduler{scoreMode: binPackMode, skipNormalize: true}
+	raw := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod0", Labels: map[string]string{scoreModeLabel: mostMode.String()}}}
+	for i := 0; i < 3; i++ {
+		if got := cs.modeFor(raw); got != binPackMode {
+			t.Fatalf("expected the raw mode label to fall back to %s, got %s", binPackMode, got)
+		}
+	}
+	if got := strings.Count(buf.String(), "requires normalizeScores"); got != 1 {
+		t.Errorf("expected a single warning, got %d:\n%s", got, buf.String())
+	}
+	ratio := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{scoreModeLabel: balancedMode.String()}}}
+	if got := cs.modeFor(ratio); got != balancedMode {
+		t.Errorf("expected the label's mode %s, got %s", balancedMode, got)
+	}
+}
+
+func TestCustomScheduler_ScoreWithoutNormalize(t *testing.T) {
+	// m3 doesn't report its allocatable yet
+	unknown := framework.NewNodeInfo()
+	unknown.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "m3"}})
+	unknown.Allocatable = nil
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 1000),
+		makeNodeInfoWithPods("m2", 1000, 1000, makePodWithMemory("existing", 250)),
+		unknown,
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	pod := makePodWithMemory("pod1", 250)
+	tests := []struct {
+		mode ScoreMode
+		want map[string]int64
+	}{
+		{mode: mostMode, want: map[string]int64{"m1": 100, "m2": 100, "m3": 0}},
+		{mode: leastMode, want: map[string]int64{"m1": 0, "m2": 0, "m3": 0}},
+		{mode: binPackMode, want: map[string]int64{"m1": 0, "m2": 25, "m3": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: tt.mode, skipNormalize: true, stableTiebreak: true}
+			if cs.ScoreExtensions() != nil {
+				t.Fatalf("expected no ScoreExtensions with normalization disabled")
+			}
+			for _, ni := range nodeInfos {
+				name := ni.Node().Name
+				score, status := cs.Score(context.Background(), nil, pod, name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected Score error on node %s: %v", name, status)
+				}
+				if score != tt.want[name] {
+					t.Errorf("expected node %s to score %d, got %d", name, tt.want[name], score)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_Close(t *testing.T) {
+	client := clientsetfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	registeredPlugins := []st.RegisterPluginFunc{
+		st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}
+	fh, err := st.NewFramework(
+		registeredPlugins,
+		"default-scheduler",
+		wait.NeverStop,
+		frameworkruntime.WithClientSet(client),
+		frameworkruntime.WithInformerFactory(informerFactory),
+	)
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+	// the scheduler's informers run before and after the plugin, only the
+	// plugin's own goroutines must be gone once it is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		informerFactory.Shutdown()
+	}()
+	informerFactory.Start(ctx.Done())
+	informerFactory.WaitForCacheSync(ctx.Done())
+	ignore := goleak.IgnoreCurrent()
+
+	p, err := New(&runtime.Unknown{Raw: []byte(`{"mode": "Least", "gangTimeoutSeconds": 60, "manageGangGates": true}`)}, fh)
+	if err != nil {
+		t.Fatalf("fail to create plugin: %s", err)
+	}
+	if err := p.(io.Closer).Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := p.(io.Closer).Close(); err != nil {
+		t.Errorf("unexpected error on second close: %v", err)
+	}
+	if err := goleak.Find(ignore); err != nil {
+		t.Errorf("expected the plugin's goroutines stopped: %v", err)
+	}
+	if informerFactory.Core().V1().Pods().Informer().IsStopped() {
+		t.Errorf("expected the shared pods informer still running after close")
+	}
+}
+
+func TestCustomScheduler_ScorePreferredNodeLabels(t *testing.T) {
+	zone := map[string]string{"topology.kubernetes.io/zone": "zone-a"}
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfoWithLabels("m1", 1000, 200, map[string]string{"topology.kubernetes.io/zone": "zone-b"}),
+		makeNodeInfoWithLabels("m2", 1000, 200, zone),
+	}
+	for _, mode := range []ScoreMode{leastMode, mostMode} {
+		t.Run(mode.String(), func(t *testing.T) {
 			client := clientsetfake.NewSimpleClientset()
 			informerFactory := informers.NewSharedInformerFactory(client, 0)
 			registeredPlugins := []st.RegisterPluginFunc{
 				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
 				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
-				st.RegisterPluginAsExtensions(Name, New, "Score"),
 			}
-			fakeSharedLister := &fakeSharedLister{nodes: tt.nodeInfos}
-
 			fh, err := st.NewFramework(
 				registeredPlugins,
 				"default-scheduler",
 				wait.NeverStop,
 				frameworkruntime.WithClientSet(client),
 				frameworkruntime.WithInformerFactory(informerFactory),
-				frameworkruntime.WithSnapshotSharedLister(fakeSharedLister),
+				frameworkruntime.WithSnapshotSharedLister(&fakeSharedLister{nodes: nodeInfos}),
 			)
 			if err != nil {
 				t.Fatalf("fail to create framework: %s", err)
 			}
 
 			cs := &CustomScheduler{
-				handle:    fh,
-				scoreMode: tt.mode,
+				handle:                    fh,
+				scoreMode:                 mode,
+				preferredNodeLabels:       zone,
+				preferredNodeLabelsWeight: defaultPreferredNodeLabelsWeight,
 			}
 
-			highest := int64(math.MinInt64)
-			bestNode := ""
-			for _, nodeName := range tt.args.nodeNames {
-				got, status := cs.Score(tt.args.ctx, tt.args.state, tt.args.pod, nodeName)
-				if !status.IsSuccess() {
-					t.Errorf("unexpected error: %v", status)
-				}
+			pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+			other, status := cs.Score(context.Background(), nil, pod, "m1")
+			if !status.IsSuccess() {
+				t.Fatalf("unexpected error: %v", status)
+			}
+			matching, status := cs.Score(context.Background(), nil, pod, "m2")
+			if !status.IsSuccess() {
+				t.Fatalf("unexpected error: %v", status)
+			}
+			if matching <= other {
+				t.Errorf("expected matching node to outscore the other, got %d and %d", matching, other)
+			}
+		})
+	}
+}
 
-				if got > highest {
-					highest = got
-					bestNode = nodeName
-				}
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		wantErr bool
+	}{
+		{name: "default curve", args: `{"mode": "Least"}`},
+		{name: "log curve", args: `{"mode": "Most", "scoreCurve": "log"}`},
+		{name: "step curve", args: `{"mode": "Most", "scoreCurve": "step"}`},
+		{name: "neutral mode", args: `{"mode": "Neutral"}`},
+		{name: "invalid mode", args: `{"mode": "Random"}`, wantErr: true},
+		{name: "invalid curve", args: `{"mode": "Least", "scoreCurve": "cubic"}`, wantErr: true},
+		{name: "hard fail deadline", args: `{"mode": "Least", "hardFailAfterSeconds": 300}`},
+		{name: "negative hard fail deadline", args: `{"mode": "Least", "hardFailAfterSeconds": -1}`, wantErr: true},
+		{name: "malformed args", args: `{"mode": "Least",`, wantErr: true},
+		{name: "mistyped args", args: `{"mode": "Least", "maxActiveGroups": "4"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&runtime.Unknown{Raw: []byte(tt.args)}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
 			}
+		})
+	}
+}
 
-			if bestNode != tt.want {
-				t.Errorf("bestNode is = %v, want %v", bestNode, tt.want)
+func TestNew_InvalidModeError(t *testing.T) {
+	_, err := New(&runtime.Unknown{Raw: []byte(`{"mode": "Spread"}`)}, nil)
+	if err == nil {
+		t.Fatal("expected an error for mode Spread")
+	}
+	for _, want := range []string{"got Spread", "args.mode", "Least, Most, BinPack, Neutral"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error %q to contain %q", err, want)
+		}
+	}
+}
+
+func TestNew_Defaults(t *testing.T) {
+	tests := []struct {
+		name                string
+		obj                 runtime.Object
+		skipDaemonSetPods   bool
+		skipUngrouped       bool
+		skipGangGating      bool
+		defaultMinAvailable int
+	}{
+		{name: "no args", obj: nil, skipDaemonSetPods: true, skipUngrouped: true, defaultMinAvailable: 1},
+		{name: "args without the fields", obj: &runtime.Unknown{Raw: []byte(`{"mode": "Least"}`)}, skipDaemonSetPods: true, skipUngrouped: true, defaultMinAvailable: 1},
+		{
+			name:              "explicitly disabled",
+			obj:               &runtime.Unknown{Raw: []byte(`{"mode": "Least", "skipDaemonSetPods": false, "skipUngrouped": false, "gangGating": false, "defaultMinAvailable": 0}`)},
+			skipDaemonSetPods: false,
+			skipUngrouped:     false,
+			skipGangGating:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.obj, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := p.(*CustomScheduler).skipDaemonSetPods; got != tt.skipDaemonSetPods {
+				t.Errorf("expected skipDaemonSetPods %v, got %v", tt.skipDaemonSetPods, got)
+			}
+			if got := p.(*CustomScheduler).skipUngrouped; got != tt.skipUngrouped {
+				t.Errorf("expected skipUngrouped %v, got %v", tt.skipUngrouped, got)
+			}
+			if got := p.(*CustomScheduler).skipGangGating; got != tt.skipGangGating {
+				t.Errorf("expected skipGangGating %v, got %v", tt.skipGangGating, got)
+			}
+			if got := p.(*CustomScheduler).defaultMinAvailable; got != tt.defaultMinAvailable {
+				t.Errorf("expected defaultMinAvailable %d, got %d", tt.defaultMinAvailable, got)
+			}
+		})
+	}
+}
+
+func TestNew_DefaultMode(t *testing.T) {
+	defer func(mode ScoreMode) { DefaultMode = mode }(DefaultMode)
+
+	tests := []struct {
+		name        string
+		defaultMode ScoreMode
+		obj         runtime.Object
+		want        ScoreMode
+	}{
+		{name: "built-in default", defaultMode: "", obj: nil, want: leastMode},
+		{name: "overridden default", defaultMode: mostMode, obj: nil, want: mostMode},
+		{name: "args without mode", defaultMode: mostMode, obj: &runtime.Unknown{Raw: []byte(`{"scoreCurve": "log"}`)}, want: mostMode},
+		{name: "explicit mode wins", defaultMode: mostMode, obj: &runtime.Unknown{Raw: []byte(`{"mode": "BinPack"}`)}, want: binPackMode},
+		{name: "invalid default", defaultMode: "Random", obj: nil, want: leastMode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			DefaultMode = tt.defaultMode
+			p, err := New(tt.obj, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := p.(*CustomScheduler).scoreMode; got != tt.want {
+				t.Errorf("expected mode %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNew_ModeCase(t *testing.T) {
+	tests := []struct {
+		mode    ScoreMode
+		want    ScoreMode
+		wantErr bool
+	}{
+		{mode: "least", want: leastMode},
+		{mode: "MOST", want: mostMode},
+		{mode: "Balanced ", want: balancedMode},
+		{mode: " binpack", want: binPackMode},
+		{mode: "Random", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			raw := fmt.Sprintf(`{"mode": %q, "namespaceModes": {"batch": %q}}`, tt.mode, tt.mode)
+			p, err := New(&runtime.Unknown{Raw: []byte(raw)}, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected mode %q to be rejected", tt.mode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			cs := p.(*CustomScheduler)
+			if cs.scoreMode != tt.want {
+				t.Errorf("expected mode %s, got %s", tt.want, cs.scoreMode)
+			}
+			if got := cs.namespaceModes["batch"]; got != tt.want {
+				t.Errorf("expected namespace mode %s, got %s", tt.want, got)
 			}
 		})
 	}
@@ -318,6 +3019,160 @@ func TestCustomScheduler_NormalizeScore(t *testing.T) {
 	}
 }
 
+func TestCustomScheduler_NormalizeScoreBounds(t *testing.T) {
+	scores := framework.NodeScoreList{
+		{Name: "m1", Score: 1000},
+		{Name: "m2", Score: 1500},
+		{Name: "m3", Score: 2000},
+		{Name: "registering", Score: worstScore},
+	}
+	cs := &CustomScheduler{normalizeMin: 0, normalizeMax: 10}
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+	if status := cs.NormalizeScore(context.Background(), nil, pod, scores); !status.IsSuccess() {
+		t.Fatalf("unexpected error: %v", status)
+	}
+	want := map[string]int64{"m1": 0, "m2": 5, "m3": 10, "registering": 0}
+	for _, s := range scores {
+		if s.Score != want[s.Name] {
+			t.Errorf("expected node %s to normalize to %d, got %d", s.Name, want[s.Name], s.Score)
+		}
+	}
+}
+
+func TestCustomScheduler_NormalizeScoreParallel(t *testing.T) {
+	// enough nodes for several chunks, the extremes in different ones
+	n := 5*normalizeChunkSize + 7
+	raw := make(framework.NodeScoreList, 0, n)
+	for i := 0; i < n; i++ {
+		score := int64((i*7919)%n) << 20
+		if i%97 == 0 {
+			score = worstScore
+		}
+		raw = append(raw, framework.NodeScore{Name: fmt.Sprintf("node%d", i), Score: score})
+	}
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	serial := append(framework.NodeScoreList(nil), raw...)
+	if status := (&CustomScheduler{}).NormalizeScore(context.Background(), nil, pod, serial); !status.IsSuccess() {
+		t.Fatalf("unexpected error: %v", status)
+	}
+	cs := &CustomScheduler{handle: newTestHandle(t, nil, nil)}
+	for run := 0; run < 3; run++ {
+		parallel := append(framework.NodeScoreList(nil), raw...)
+		if status := cs.NormalizeScore(context.Background(), nil, pod, parallel); !status.IsSuccess() {
+			t.Fatalf("unexpected error: %v", status)
+		}
+		if !reflect.DeepEqual(parallel, serial) {
+			t.Fatalf("expected parallel normalization to match the serial one on run %d", run)
+		}
+	}
+	if serial[0].Score != framework.MinNodeScore {
+		t.Errorf("expected unscorable node normalized to %d, got %d", framework.MinNodeScore, serial[0].Score)
+	}
+}
+
+func TestCustomScheduler_NormalizeScoreClamps(t *testing.T) {
+	registerMetrics()
+	before, err := testutil.GetCounterMetricValue(scoreClampedTotal)
+	if err != nil {
+		t.Fatalf("fail to read metric: %s", err)
+	}
+
+	// equal raw scores skip the mapping and stay far out of range
+	scores := framework.NodeScoreList{
+		{Name: "m1", Score: 4096},
+		{Name: "m2", Score: 4096},
+		{Name: "m3", Score: 4096},
+	}
+	cs := &CustomScheduler{}
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+	if status := cs.NormalizeScore(context.Background(), nil, pod, scores); !status.IsSuccess() {
+		t.Fatalf("unexpected error: %v", status)
+	}
+	for _, s := range scores {
+		if s.Score != framework.MaxNodeScore {
+			t.Errorf("expected node %s clamped to %d, got %d", s.Name, framework.MaxNodeScore, s.Score)
+		}
+	}
+
+	// a range that overflows the mapping
+	scores = framework.NodeScoreList{
+		{Name: "m1", Score: math.MinInt64 + 1},
+		{Name: "m2", Score: math.MaxInt64},
+	}
+	if status := cs.NormalizeScore(context.Background(), nil, pod, scores); !status.IsSuccess() {
+		t.Fatalf("unexpected error: %v", status)
+	}
+	for _, s := range scores {
+		if s.Score < framework.MinNodeScore || s.Score > framework.MaxNodeScore {
+			t.Errorf("expected node %s within range, got %d", s.Name, s.Score)
+		}
+	}
+
+	after, err := testutil.GetCounterMetricValue(scoreClampedTotal)
+	if err != nil {
+		t.Fatalf("fail to read metric: %s", err)
+	}
+	if after-before < 3 {
+		t.Errorf("expected at least 3 clamped scores counted, got %v", after-before)
+	}
+}
+
+func TestCustomScheduler_NormalizeScoreLogsTable(t *testing.T) {
+	var fs flag.FlagSet
+	klog.InitFlags(&fs)
+	var buf bytes.Buffer
+	fs.Set("logtostderr", "false")
+	klog.SetOutput(&buf)
+	defer func() {
+		fs.Set("v", "0")
+		fs.Set("logtostderr", "true")
+		klog.SetOutput(os.Stderr)
+	}()
+
+	normalize := func() {
+		scores := framework.NodeScoreList{
+			{Name: "m1", Score: 1},
+			{Name: "m2", Score: 2},
+			{Name: "m3", Score: 3},
+		}
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod0", Namespace: "default"}}
+		cs := &CustomScheduler{}
+		if status := cs.NormalizeScore(context.Background(), nil, pod, scores); !status.IsSuccess() {
+			t.Fatalf("unexpected error: %v", status)
+		}
+		klog.Flush()
+	}
+
+	// silent at default verbosity
+	fs.Set("v", "0")
+	normalize()
+	if strings.Contains(buf.String(), "Score table") {
+		t.Errorf("expected no score table at default verbosity, got %q", buf.String())
+	}
+
+	fs.Set("v", "5")
+	normalize()
+	out := buf.String()
+	lines := []string{
+		"Score table for pod default/pod0",
+		"node m3: raw=3 normalized=100",
+		"node m2: raw=2 normalized=50",
+		"node m1: raw=1 normalized=0",
+	}
+	last := -1
+	for _, line := range lines {
+		i := strings.Index(out, line)
+		if i < 0 {
+			t.Fatalf("expected output to contain %q, got %q", line, out)
+		}
+		if i < last {
+			t.Errorf("expected %q to come later in %q", line, out)
+		}
+		last = i
+	}
+}
+
 func makeNodeInfo(node string, milliCPU, memory int64) *framework.NodeInfo {
 	ni := framework.NewNodeInfo()
 	ni.SetNode(&v1.Node{
@@ -336,6 +3191,50 @@ func makeNodeInfo(node string, milliCPU, memory int64) *framework.NodeInfo {
 	return ni
 }
 
+func makeNodeInfoWithEphemeralStorage(node string, milliCPU, memory, storage int64) *framework.NodeInfo {
+	ni := framework.NewNodeInfo()
+	ni.SetNode(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: node},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:              *resource.NewMilliQuantity(milliCPU, resource.DecimalSI),
+				v1.ResourceMemory:           *resource.NewQuantity(memory, resource.BinarySI),
+				v1.ResourceEphemeralStorage: *resource.NewQuantity(storage, resource.BinarySI),
+			},
+		},
+	})
+	return ni
+}
+
+func makeNodeInfoWithLabels(node string, milliCPU, memory int64, labels map[string]string) *framework.NodeInfo {
+	ni := makeNodeInfo(node, milliCPU, memory)
+	ni.Node().Labels = labels
+	return ni
+}
+
+func makeNodeInfoWithPods(node string, milliCPU, memory int64, pods ...*v1.Pod) *framework.NodeInfo {
+	ni := makeNodeInfo(node, milliCPU, memory)
+	for _, p := range pods {
+		ni.AddPod(p)
+	}
+	return ni
+}
+
+func makePodWithMemory(name string, memory int64) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceMemory: *resource.NewQuantity(memory, resource.BinarySI),
+					},
+				},
+			}},
+		},
+	}
+}
+
 var _ framework.SharedLister = &fakeSharedLister{}
 
 type fakeSharedLister struct {
@@ -0,0 +1,93 @@
+package plugins
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Scorer computes the raw score of a node for the pod in a scoring mode.
+// Scores only need to order the nodes, NormalizeScore maps them to the
+// framework's range afterwards, and the plugin's own adjustments, e.g. the
+// cost penalty or the affinity bonuses, still apply on top.
+type Scorer interface {
+	Score(nodeInfo *framework.NodeInfo, pod *v1.Pod) (int64, error)
+}
+
+var (
+	scorersMu sync.RWMutex
+	// registeredScorers are the scorers of the modes added by RegisterScorer.
+	registeredScorers = make(map[ScoreMode]Scorer)
+)
+
+// RegisterScorer adds a scoring mode computed by the scorer, so teams can
+// bring their own heuristics without forking the plugin. Register it before
+// the scheduler builds the plugin, e.g. in main next to app.WithPlugin, then
+// select it through the mode arg or the scoreMode label like any other mode.
+func RegisterScorer(mode ScoreMode, scorer Scorer) error {
+	if mode == "" || scorer == nil {
+		return fmt.Errorf("a scorer needs a mode and an implementation")
+	}
+	for _, known := range knownModes {
+		if strings.EqualFold(mode.String(), known.String()) {
+			return fmt.Errorf("mode %s is built in", known)
+		}
+	}
+	scorersMu.Lock()
+	defer scorersMu.Unlock()
+	for registered := range registeredScorers {
+		if strings.EqualFold(mode.String(), registered.String()) {
+			return fmt.Errorf("mode %s is already registered", registered)
+		}
+	}
+	registeredScorers[mode] = scorer
+	return nil
+}
+
+// registeredModes lists the modes added by RegisterScorer, sorted.
+func registeredModes() []ScoreMode {
+	scorersMu.RLock()
+	defer scorersMu.RUnlock()
+	modes := make([]ScoreMode, 0, len(registeredScorers))
+	for mode := range registeredScorers {
+		modes = append(modes, mode)
+	}
+	sort.Slice(modes, func(i, j int) bool { return modes[i] < modes[j] })
+	return modes
+}
+
+// memoryScorer is the built-in Scorer of the Least and Most modes.
+type memoryScorer struct {
+	cs   *CustomScheduler
+	most bool
+}
+
+// Score implements Scorer.
+func (s memoryScorer) Score(nodeInfo *framework.NodeInfo, pod *v1.Pod) (int64, error) {
+	score := applyCurve(s.cs.scoreCurve, s.cs.memoryValue(nodeInfo, pod))
+	if !s.most {
+		score = -score
+	}
+	return score, nil
+}
+
+// scorerFor returns the Scorer of the mode, nil for the built-in modes
+// scored by Score itself.
+func (cs *CustomScheduler) scorerFor(mode ScoreMode) Scorer {
+	if cs.scorer != nil && mode == cs.scoreMode {
+		return cs.scorer
+	}
+	switch mode {
+	case leastMode:
+		return memoryScorer{cs: cs}
+	case mostMode:
+		return memoryScorer{cs: cs, most: true}
+	}
+	scorersMu.RLock()
+	defer scorersMu.RUnlock()
+	return registeredScorers[mode]
+}
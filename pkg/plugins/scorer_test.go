@@ -0,0 +1,102 @@
+package plugins
+
+import (
+	"sync"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// fakeScorer scores nodes from a fixed table and records the nodes scored.
+type fakeScorer struct {
+	scores map[string]int64
+
+	mu     sync.Mutex
+	scored []string
+}
+
+func (s *fakeScorer) Score(nodeInfo *framework.NodeInfo, pod *v1.Pod) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scored = append(s.scored, nodeInfo.Node().Name)
+	return s.scores[nodeInfo.Node().Name], nil
+}
+
+// registerTestScorer registers the scorer for the duration of the test.
+func registerTestScorer(t *testing.T, mode ScoreMode, scorer Scorer) {
+	t.Helper()
+	if err := RegisterScorer(mode, scorer); err != nil {
+		t.Fatalf("fail to register scorer: %s", err)
+	}
+	t.Cleanup(func() {
+		scorersMu.Lock()
+		defer scorersMu.Unlock()
+		delete(registeredScorers, mode)
+	})
+}
+
+func TestRegisterScorer(t *testing.T) {
+	registerTestScorer(t, "NodeOrder", &fakeScorer{})
+	tests := []struct {
+		name    string
+		mode    ScoreMode
+		scorer  Scorer
+		wantErr bool
+	}{
+		{name: "new mode", mode: "Spread", scorer: &fakeScorer{}},
+		{name: "no mode", scorer: &fakeScorer{}, wantErr: true},
+		{name: "no scorer", mode: "Pack", wantErr: true},
+		{name: "built-in mode", mode: "least", scorer: &fakeScorer{}, wantErr: true},
+		{name: "registered mode", mode: "nodeorder", scorer: &fakeScorer{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RegisterScorer(tt.mode, tt.scorer)
+			if err == nil {
+				scorersMu.Lock()
+				delete(registeredScorers, tt.mode)
+				scorersMu.Unlock()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_ScoreRegisteredScorer(t *testing.T) {
+	scorer := &fakeScorer{scores: map[string]int64{"m1": 30, "m2": 10, "m3": 20}}
+	registerTestScorer(t, "NodeOrder", scorer)
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 300),
+		makeNodeInfo("m3", 1000, 200),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+
+	// registered modes parse like the built-in ones
+	p, err := New(&runtime.Unknown{Raw: []byte(`{"mode": "nodeorder"}`)}, fh)
+	if err != nil {
+		t.Fatalf("fail to create plugin: %s", err)
+	}
+	cs := p.(*CustomScheduler)
+	defer func() {
+		cs.Close()
+		fh.SharedInformerFactory().Shutdown()
+	}()
+	if cs.scorer != Scorer(scorer) {
+		t.Fatalf("expected the registered scorer selected, got %v", cs.scorer)
+	}
+
+	want := map[string]int64{"m1": 100, "m2": 0, "m3": 50}
+	for _, s := range scoreNodes(t, cs, nil, &v1.Pod{}, nodeInfos) {
+		if s.Score != want[s.Name] {
+			t.Errorf("expected node %s to score %d, got %d", s.Name, want[s.Name], s.Score)
+		}
+	}
+	if len(scorer.scored) != len(nodeInfos) {
+		t.Errorf("expected the scorer invoked for every node, got %v", scorer.scored)
+	}
+}
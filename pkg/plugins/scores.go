@@ -0,0 +1,81 @@
+package plugins
+
+import (
+	"sync"
+
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// ScoresStateKey is the CycleState key under which the plugin publishes the
+// raw score, before normalization, of every node it scored. PreScore writes
+// an empty NodeScores at the start of each scheduling cycle, Score fills it
+// in, and it is dropped with the cycle's CycleState. Score runs in parallel
+// with the Score of other plugins, so read it from a later extension point,
+// e.g. NormalizeScore, Reserve or PreBind, preferably through ReadScore.
+const ScoresStateKey framework.StateKey = "CustomScheduler/scores"
+
+// NodeScores holds the raw scores of the current scheduling cycle by node
+// name. It is safe for concurrent use.
+type NodeScores struct {
+	mu     sync.RWMutex
+	scores map[string]int64
+}
+
+// Clone implements framework.StateData.
+func (s *NodeScores) Clone() framework.StateData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	scores := make(map[string]int64, len(s.scores))
+	for k, v := range s.scores {
+		scores[k] = v
+	}
+	return &NodeScores{scores: scores}
+}
+
+func (s *NodeScores) set(nodeName string, score int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scores[nodeName] = score
+}
+
+// Get returns the raw score of the node, ok is false when the node wasn't
+// scored in this cycle.
+func (s *NodeScores) Get(nodeName string) (int64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	score, ok := s.scores[nodeName]
+	return score, ok
+}
+
+// ReadScore returns the raw score the plugin gave the node in the scheduling
+// cycle of the state. ok is false when the plugin didn't score the node, or
+// didn't run in this cycle.
+func ReadScore(state *framework.CycleState, nodeName string) (int64, bool) {
+	if state == nil {
+		return 0, false
+	}
+	data, err := state.Read(ScoresStateKey)
+	if err != nil {
+		return 0, false
+	}
+	s, ok := data.(*NodeScores)
+	if !ok {
+		return 0, false
+	}
+	return s.Get(nodeName)
+}
+
+// recordScore publishes the raw score of the node. It does nothing when
+// PreScore didn't run, e.g. in tests calling Score directly.
+func recordScore(state *framework.CycleState, nodeName string, score int64) {
+	if state == nil {
+		return
+	}
+	data, err := state.Read(ScoresStateKey)
+	if err != nil {
+		return
+	}
+	if s, ok := data.(*NodeScores); ok {
+		s.set(nodeName, score)
+	}
+}
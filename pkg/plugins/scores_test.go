@@ -0,0 +1,45 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestReadScore(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 200),
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode}
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{}}}
+
+	state := framework.NewCycleState()
+	if _, ok := ReadScore(state, "m1"); ok {
+		t.Fatalf("expected no score before PreScore")
+	}
+	if status := cs.PreScore(context.Background(), state, pod, []*v1.Node{nodeInfos[0].Node(), nodeInfos[1].Node()}); !status.IsSuccess() {
+		t.Fatalf("unexpected PreScore error: %v", status)
+	}
+	for _, ni := range nodeInfos {
+		want, status := cs.Score(context.Background(), state, pod, ni.Node().Name)
+		if !status.IsSuccess() {
+			t.Fatalf("unexpected Score error: %v", status)
+		}
+		got, ok := ReadScore(state, ni.Node().Name)
+		if !ok || got != want {
+			t.Errorf("expected score %d for node %s, got %d (found %v)", want, ni.Node().Name, got, ok)
+		}
+	}
+	if _, ok := ReadScore(state, "m3"); ok {
+		t.Errorf("expected no score for a node that wasn't scored")
+	}
+
+	// the published scores survive a CycleState clone
+	if got, ok := ReadScore(state.Clone(), "m2"); !ok || got != 200 {
+		t.Errorf("expected cloned score 200 for node m2, got %d (found %v)", got, ok)
+	}
+}
@@ -0,0 +1,115 @@
+package plugins
+
+import (
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// scoreStateKey is the CycleState key of the scoring cycle's scoreStateData.
+const scoreStateKey = framework.StateKey(Name + "/scoreState")
+
+// scoreStateData carries how the pod is scored in the current cycle from
+// PreScore and Score to NormalizeScore: the mode and the resource it scores
+// by, the zone of its placed group members, and the range of the raw scores
+// given. Score runs for several nodes in
+// parallel, so the range is updated under the mutex.
+type scoreStateData struct {
+	mode     ScoreMode
+	resource string
+	// groupZone is empty unless GroupZoneAffinity found a zone.
+	groupZone string
+
+	mu sync.Mutex
+	// scored is false until Score gave a node a regular raw score, nodes
+	// given worstScore don't count toward the range.
+	scored         bool
+	minRaw, maxRaw int64
+}
+
+// Clone implements framework.StateData.
+func (d *scoreStateData) Clone() framework.StateData {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return &scoreStateData{mode: d.mode, resource: d.resource, groupZone: d.groupZone, scored: d.scored, minRaw: d.minRaw, maxRaw: d.maxRaw}
+}
+
+// observe widens the raw score range to the score.
+func (d *scoreStateData) observe(score int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.scored {
+		d.scored, d.minRaw, d.maxRaw = true, score, score
+		return
+	}
+	if score < d.minRaw {
+		d.minRaw = score
+	}
+	if score > d.maxRaw {
+		d.maxRaw = score
+	}
+}
+
+// rawRange returns the range of the raw scores. ok is false when no node got
+// a regular score.
+func (d *scoreStateData) rawRange() (int64, int64, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.minRaw, d.maxRaw, d.scored
+}
+
+// newScoreState describes how the pod is scored in this cycle.
+func (cs *CustomScheduler) newScoreState(pod *v1.Pod) *scoreStateData {
+	mode := cs.modeFor(pod)
+	d := &scoreStateData{mode: mode, resource: cs.scoredResource(pod, mode)}
+	if cs.groupZoneAffinity {
+		d.groupZone, _ = cs.groupZone(pod)
+	}
+	return d
+}
+
+// scoredResource names what the mode scores the pod's nodes by.
+func (cs *CustomScheduler) scoredResource(pod *v1.Pod, mode ScoreMode) string {
+	switch mode {
+	case leastMode, mostMode, blendMode:
+		if scoreBy := cs.scoreByFor(pod); scoreBy != "" {
+			return scoreBy
+		}
+		return memoryScoreBy
+	case binPackMode:
+		return memoryScoreBy
+	case balancedMode, dominantMode:
+		return "cpu and memory"
+	case leastEphemeralStorageMode, mostEphemeralStorageMode:
+		return string(v1.ResourceEphemeralStorage)
+	case leastPodHeadroomMode, mostPodHeadroomMode:
+		return string(v1.ResourcePods)
+	case leastAnnotationMode, mostAnnotationMode:
+		return cs.annotationKey
+	case leastGPUMemoryMode, mostGPUMemoryMode:
+		return string(cs.gpuMemoryResourceName())
+	}
+	return ""
+}
+
+// readScoreState returns the scoring cycle's scoreStateData, ok is false when
+// PreScore didn't run, e.g. in tests calling Score directly.
+func readScoreState(state *framework.CycleState) (*scoreStateData, bool) {
+	if state == nil {
+		return nil, false
+	}
+	data, err := state.Read(scoreStateKey)
+	if err != nil {
+		return nil, false
+	}
+	d, ok := data.(*scoreStateData)
+	return d, ok
+}
+
+// observeRawScore widens the cycle's raw score range to the score.
+func observeRawScore(state *framework.CycleState, score int64) {
+	if d, ok := readScoreState(state); ok {
+		d.observe(score)
+	}
+}
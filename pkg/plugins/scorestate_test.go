@@ -0,0 +1,100 @@
+package plugins
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+func TestScoreStateData_Clone(t *testing.T) {
+	d := &scoreStateData{mode: leastEphemeralStorageMode, resource: string(v1.ResourceEphemeralStorage)}
+	d.observe(-200)
+	d.observe(-100)
+
+	state := framework.NewCycleState()
+	state.Write(scoreStateKey, d)
+	clone := state.Clone()
+	got, ok := readScoreState(clone)
+	if !ok {
+		t.Fatalf("expected the score state in the cloned cycle state")
+	}
+	if got == d {
+		t.Fatalf("expected the clone to be a copy")
+	}
+	if got.mode != d.mode || got.resource != d.resource {
+		t.Errorf("expected mode %s on %s, got mode %s on %s", d.mode, d.resource, got.mode, got.resource)
+	}
+	if minRaw, maxRaw, scored := got.rawRange(); !scored || minRaw != -200 || maxRaw != -100 {
+		t.Errorf("expected raw range [-200,-100], got [%d,%d] (scored %v)", minRaw, maxRaw, scored)
+	}
+
+	// the copy evolves on its own
+	got.observe(-300)
+	if minRaw, _, _ := d.rawRange(); minRaw != -200 {
+		t.Errorf("expected the original range to stay at -200, got %d", minRaw)
+	}
+}
+
+func TestCustomScheduler_NormalizeScoreReadsScoreState(t *testing.T) {
+	registering := makeNodeInfo("registering", 0, 0)
+	registering.Allocatable = nil
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 200),
+		makeNodeInfo("m3", 1000, 400),
+		registering,
+	}
+	fh := newTestHandle(t, nodeInfos, nil)
+	nodes := make([]*v1.Node, 0, len(nodeInfos))
+	for _, ni := range nodeInfos {
+		nodes = append(nodes, ni.Node())
+	}
+
+	tests := []struct {
+		name         string
+		cs           *CustomScheduler
+		pod          *v1.Pod
+		wantMode     ScoreMode
+		wantResource string
+	}{
+		{
+			name:         "configured mode",
+			cs:           &CustomScheduler{handle: fh, scoreMode: leastMode},
+			pod:          st.MakePod().Name("pod0").Obj(),
+			wantMode:     leastMode,
+			wantResource: memoryScoreBy,
+		},
+		{
+			name:         "pod label mode",
+			cs:           &CustomScheduler{handle: fh, scoreMode: leastMode, scoreBy: podCapacityScoreBy},
+			pod:          st.MakePod().Name("pod0").Label("scoreMode", "Most").Obj(),
+			wantMode:     mostMode,
+			wantResource: podCapacityScoreBy,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := framework.NewCycleState()
+			if status := tt.cs.PreScore(context.Background(), state, tt.pod, nodes); !status.IsSuccess() {
+				t.Fatalf("unexpected PreScore error: %v", status)
+			}
+			got := scoreNodes(t, tt.cs, state, tt.pod, nodeInfos)
+			want := scoreNodes(t, tt.cs, nil, tt.pod, nodeInfos)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("expected the same scores as without the score state, got %v, want %v", got, want)
+			}
+
+			d, ok := readScoreState(state)
+			if !ok {
+				t.Fatalf("expected PreScore to write the score state")
+			}
+			if d.mode != tt.wantMode || d.resource != tt.wantResource {
+				t.Errorf("expected mode %s on %s, got mode %s on %s", tt.wantMode, tt.wantResource, d.mode, d.resource)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package plugins
+
+import (
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// gangShortfallStateKey is the CycleState key of the size of an under-quota
+// group let through PreFilter by SoftGang.
+const gangShortfallStateKey = framework.StateKey(Name + "/gangShortfall")
+
+// gangShortfall is how far the pod's group is from its minimum.
+type gangShortfall struct {
+	observed, required int
+}
+
+// Clone implements framework.StateData.
+func (g *gangShortfall) Clone() framework.StateData {
+	return g
+}
+
+// recordGangShortfall stores the size of the pod's under-quota group for
+// NormalizeScore to score it down.
+func recordGangShortfall(state *framework.CycleState, observed, required int) {
+	if state == nil {
+		return
+	}
+	state.Write(gangShortfallStateKey, &gangShortfall{observed: observed, required: required})
+}
+
+// gangShortfallRatio returns the fraction of its minimum the pod's group
+// reached. ok is false when the group isn't under quota.
+func gangShortfallRatio(state *framework.CycleState) (float64, bool) {
+	if state == nil {
+		return 0, false
+	}
+	data, err := state.Read(gangShortfallStateKey)
+	if err != nil {
+		return 0, false
+	}
+	g := data.(*gangShortfall)
+	return float64(g.observed) / float64(g.required), true
+}
@@ -0,0 +1,150 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+func TestCustomScheduler_SoftGang(t *testing.T) {
+	member := func(name, group, minAvailable string) *v1.Pod {
+		return st.MakePod().Name(name).Namespace("default").Label("podGroup", group).Label("minAvailable", minAvailable).Obj()
+	}
+	short := member("short0", "short", "4")
+	complete := member("complete0", "complete", "1")
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 200),
+	}
+	fh := newTestHandle(t, nodeInfos, []*v1.Pod{short, complete})
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode, softGang: true}
+
+	scores := make(map[string]map[string]int64)
+	for _, pod := range []*v1.Pod{short, complete} {
+		state := framework.NewCycleState()
+		if _, status := cs.PreFilter(context.Background(), state, pod); !status.IsSuccess() {
+			t.Fatalf("expected pod %s through PreFilter, got %v", pod.Name, status)
+		}
+		scores[pod.Name] = make(map[string]int64)
+		for _, s := range scoreNodes(t, cs, state, pod, nodeInfos) {
+			scores[pod.Name][s.Name] = s.Score
+		}
+	}
+
+	// the short group has a quarter of its minimum
+	if got := scores["short0"]["m2"]; got != 25 {
+		t.Errorf("expected the under-quota pod to score 25 on m2, got %d", got)
+	}
+	if got := scores["complete0"]["m2"]; got != 100 {
+		t.Errorf("expected the at-quota pod to score 100 on m2, got %d", got)
+	}
+	for _, ni := range nodeInfos {
+		name := ni.Node().Name
+		if scores["short0"][name] > scores["complete0"][name] {
+			t.Errorf("expected the under-quota pod to score at most %d on %s, got %d", scores["complete0"][name], name, scores["short0"][name])
+		}
+	}
+}
+
+// fixedScorePlugin stands in for another score plugin of the profile,
+// scoring nodes from a fixed table.
+type fixedScorePlugin struct {
+	scores map[string]int64
+}
+
+func (p *fixedScorePlugin) Name() string {
+	return "FixedScore"
+}
+
+func (p *fixedScorePlugin) Score(_ context.Context, _ *framework.CycleState, _ *v1.Pod, nodeName string) (int64, *framework.Status) {
+	return p.scores[nodeName], nil
+}
+
+func (p *fixedScorePlugin) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
+
+func TestCustomScheduler_SoftGangRanking(t *testing.T) {
+	member := func(name, group, minAvailable string) *v1.Pod {
+		return st.MakePod().Name(name).Namespace("default").Label("podGroup", group).Label("minAvailable", minAvailable).Obj()
+	}
+	short := member("short0", "short", "4")
+	complete := member("complete0", "complete", "1")
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 200),
+	}
+	nodes := []*v1.Node{nodeInfos[0].Node(), nodeInfos[1].Node()}
+
+	// Most prefers m2, the other plugin, e.g. a spreading one, prefers m1
+	cs := &CustomScheduler{scoreMode: mostMode, softGang: true}
+	factory := func(_ runtime.Object, h framework.Handle) (framework.Plugin, error) {
+		cs.handle = h
+		return cs, nil
+	}
+	other := func(_ runtime.Object, _ framework.Handle) (framework.Plugin, error) {
+		return &fixedScorePlugin{scores: map[string]int64{"m1": 60, "m2": 0}}, nil
+	}
+	fwk := newTestFramework(t, []st.RegisterPluginFunc{
+		st.RegisterPluginAsExtensions(Name, factory, "PreFilter", "Score"),
+		st.RegisterScorePlugin("FixedScore", other, 1),
+	}, nodeInfos, []*v1.Pod{short, complete})
+
+	best := func(pod *v1.Pod) string {
+		ctx := context.Background()
+		state := framework.NewCycleState()
+		if _, status := fwk.RunPreFilterPlugins(ctx, state, pod); !status.IsSuccess() {
+			t.Fatalf("expected pod %s through PreFilter, got %v", pod.Name, status)
+		}
+		scores, status := fwk.RunScorePlugins(ctx, state, pod, nodes)
+		if !status.IsSuccess() {
+			t.Fatalf("unexpected error: %v", status)
+		}
+		top := scores[0]
+		for _, s := range scores[1:] {
+			if s.TotalScore > top.TotalScore {
+				top = s
+			}
+		}
+		return top.Name
+	}
+
+	// the complete gang packs as the mode wants, the incomplete one is
+	// scored down far enough that the other plugin places it
+	if got := best(complete); got != "m2" {
+		t.Errorf("expected the at-quota pod placed on m2, got %s", got)
+	}
+	if got := best(short); got != "m1" {
+		t.Errorf("expected the under-quota pod placed on m1, got %s", got)
+	}
+}
+
+func TestCustomScheduler_SoftGangRoleShort(t *testing.T) {
+	member := func(name, role string) *v1.Pod {
+		return st.MakePod().Name(name).Namespace("default").
+			Label("podGroup", "job1").Label("minAvailable", "3").Label("podRole", role).
+			Annotation("minAvailableByRole", `{"ps": 1, "worker": 2}`).Obj()
+	}
+	// the group reaches minAvailable, but only has half of its workers
+	pods := []*v1.Pod{member("ps0", "ps"), member("ps1", "ps"), member("worker0", "worker")}
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfo("m1", 1000, 100),
+		makeNodeInfo("m2", 1000, 200),
+	}
+	fh := newTestHandle(t, nodeInfos, pods)
+	cs := &CustomScheduler{handle: fh, scoreMode: mostMode, softGang: true}
+
+	state := framework.NewCycleState()
+	if _, status := cs.PreFilter(context.Background(), state, pods[0]); !status.IsSuccess() {
+		t.Fatalf("expected the pod through PreFilter, got %v", status)
+	}
+	for _, s := range scoreNodes(t, cs, state, pods[0], nodeInfos) {
+		if s.Name == "m2" && s.Score != 50 {
+			t.Errorf("expected the pod of the role-short gang to score 50 on m2, got %d", s.Score)
+		}
+	}
+}
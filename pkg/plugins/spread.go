@@ -0,0 +1,96 @@
+package plugins
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// defaultGroupMaxSkew is the skew allowed when GroupTopologyKey is set
+// without GroupMaxSkew.
+const defaultGroupMaxSkew int32 = 1
+
+// groupSpreadStateKey is the CycleState key of the pod's groupSpread.
+const groupSpreadStateKey = framework.StateKey(Name + "/groupSpread")
+
+// groupSpread is how the members of the pod's group are spread over the
+// domains of the group topology key, as PreFilter found them in the snapshot.
+type groupSpread struct {
+	counts map[string]int
+	min    int
+}
+
+// Clone implements framework.StateData.
+func (s *groupSpread) Clone() framework.StateData {
+	return s
+}
+
+// recordGroupSpread counts the members of the group placed in each domain of
+// the group topology key for Filter to check the skew against.
+func (cs *CustomScheduler) recordGroupSpread(state *framework.CycleState, pod *v1.Pod, group string) error {
+	if cs.groupTopologyKey == "" || state == nil || cs.handle.SnapshotSharedLister() == nil {
+		return nil
+	}
+	nodeInfos, err := cs.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		return fmt.Errorf("error listing nodes for the spread of group %s: %v", group, err)
+	}
+	spread := &groupSpread{counts: make(map[string]int)}
+	for _, ni := range nodeInfos {
+		node := ni.Node()
+		if node == nil {
+			continue
+		}
+		domain, ok := node.Labels[cs.groupTopologyKey]
+		if !ok {
+			continue
+		}
+		// domains without members count too, they're the least populated
+		n := spread.counts[domain]
+		for _, p := range ni.Pods {
+			if p.Pod.UID == pod.UID {
+				continue
+			}
+			if g, ok := cs.groupName(p.Pod); ok && g == group {
+				n++
+			}
+		}
+		spread.counts[domain] = n
+	}
+	first := true
+	for _, n := range spread.counts {
+		if first || n < spread.min {
+			spread.min, first = n, false
+		}
+	}
+	state.Write(groupSpreadStateKey, spread)
+	return nil
+}
+
+// spreadRejection rejects the node when placing the pod on it would spread
+// its group over the group topology key with more than the max skew. It
+// returns nil when the spread wasn't recorded, e.g. for ungrouped pods.
+func (cs *CustomScheduler) spreadRejection(state *framework.CycleState, node *v1.Node) *framework.Status {
+	if cs.groupTopologyKey == "" || state == nil {
+		return nil
+	}
+	data, err := state.Read(groupSpreadStateKey)
+	if err != nil {
+		return nil
+	}
+	spread := data.(*groupSpread)
+	domain, ok := node.Labels[cs.groupTopologyKey]
+	if !ok {
+		return nodeRejection(framework.UnschedulableAndUnresolvable, node.Name, "group-spread", "node has no %s label", cs.groupTopologyKey)
+	}
+	maxSkew := cs.groupMaxSkew
+	if maxSkew <= 0 {
+		maxSkew = defaultGroupMaxSkew
+	}
+	if skew := spread.counts[domain] + 1 - spread.min; skew > int(maxSkew) {
+		return nodeRejection(framework.Unschedulable, node.Name, "group-spread", "%s %s would have %d group members, skew %d exceeds %d",
+			cs.groupTopologyKey, domain, spread.counts[domain]+1, skew, maxSkew)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package plugins
+
+import (
+	"log"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// groupZoneBonusPercent is the bonus given to nodes in the zone most of the
+// pod's placed group members are in, as a percentage of the magnitude of
+// their resource score.
+const groupZoneBonusPercent int64 = 10
+
+// groupZone returns the zone most of the pod's already placed group members
+// run in, ties going to the lexically first zone. ok is false when no member
+// is placed on a node with a zone yet.
+func (cs *CustomScheduler) groupZone(pod *v1.Pod) (string, bool) {
+	if cs.handle.SharedInformerFactory() == nil || cs.handle.SnapshotSharedLister() == nil {
+		return "", false
+	}
+	_, pods, exists, err := cs.groupMembers(pod)
+	if err != nil {
+		log.Printf("Warning: can't list the group of pod %s for its zone: %v", pod.Name, err)
+		return "", false
+	}
+	if !exists {
+		return "", false
+	}
+	members := make(map[string]int)
+	for _, p := range pods {
+		if p.UID == pod.UID || p.Spec.NodeName == "" {
+			continue
+		}
+		nodeinfo, err := cs.handle.SnapshotSharedLister().NodeInfos().Get(p.Spec.NodeName)
+		if err != nil || nodeinfo.Node() == nil {
+			continue
+		}
+		if zone, ok := nodeinfo.Node().Labels[v1.LabelTopologyZone]; ok {
+			members[zone]++
+		}
+	}
+	var dominant string
+	for zone, n := range members {
+		if n > members[dominant] || (n == members[dominant] && zone < dominant) {
+			dominant = zone
+		}
+	}
+	return dominant, dominant != ""
+}
@@ -0,0 +1,80 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+func TestCustomScheduler_ScoreGroupZoneAffinity(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfoWithLabels("m1", 1000, 1000, map[string]string{v1.LabelTopologyZone: "a"}),
+		makeNodeInfoWithLabels("m2", 1000, 1050, map[string]string{v1.LabelTopologyZone: "b"}),
+	}
+	placed := st.MakePod().Name("pod0").UID("pod0").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "2").Node("m1").Obj()
+	pod := st.MakePod().Name("pod1").UID("pod1").Namespace("default").Label("podGroup", "g1").Label("minAvailable", "2").Obj()
+	fh := newTestHandle(t, nodeInfos, []*v1.Pod{placed, pod})
+
+	tests := []struct {
+		name     string
+		affinity bool
+		want     map[string]int64
+	}{
+		{name: "disabled", want: map[string]int64{"m1": 0, "m2": 100}},
+		{name: "enabled", affinity: true, want: map[string]int64{"m1": 100, "m2": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &CustomScheduler{handle: fh, scoreMode: mostMode, groupZoneAffinity: tt.affinity}
+			state := framework.NewCycleState()
+			if status := cs.PreScore(context.Background(), state, pod, []*v1.Node{nodeInfos[0].Node(), nodeInfos[1].Node()}); !status.IsSuccess() {
+				t.Fatalf("unexpected PreScore error: %v", status)
+			}
+			for _, s := range scoreNodes(t, cs, state, pod, nodeInfos) {
+				if s.Score != tt.want[s.Name] {
+					t.Errorf("expected node %s to score %d, got %d", s.Name, tt.want[s.Name], s.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomScheduler_GroupZone(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		makeNodeInfoWithLabels("m1", 1000, 1000, map[string]string{v1.LabelTopologyZone: "a"}),
+		makeNodeInfoWithLabels("m2", 1000, 1000, map[string]string{v1.LabelTopologyZone: "b"}),
+		makeNodeInfoWithLabels("m3", 1000, 1000, map[string]string{v1.LabelTopologyZone: "b"}),
+		makeNodeInfo("m4", 1000, 1000),
+	}
+	member := func(name, node string) *v1.Pod {
+		return st.MakePod().Name(name).UID(name).Namespace("default").Label("podGroup", "g1").Label("minAvailable", "5").Node(node).Obj()
+	}
+	tests := []struct {
+		name   string
+		nodes  []string
+		want   string
+		wantOK bool
+	}{
+		{name: "nothing placed", nodes: []string{"", ""}},
+		{name: "placed on a node without zone", nodes: []string{"m4"}},
+		{name: "most members", nodes: []string{"m1", "m2", "m3"}, want: "b", wantOK: true},
+		{name: "tie goes to the first zone", nodes: []string{"m2", "m1"}, want: "a", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := member("pod", "")
+			pods := []*v1.Pod{pod}
+			for i, node := range tt.nodes {
+				pods = append(pods, member(string(rune('a'+i)), node))
+			}
+			cs := &CustomScheduler{handle: newTestHandle(t, nodeInfos, pods)}
+			got, ok := cs.groupZone(pod)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("expected zone %q, %v, got %q, %v", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+}